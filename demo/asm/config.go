@@ -0,0 +1,4 @@
+package asm
+
+// mapmap:config match:"ignoreCase"
+type BaseConfig struct{}
@@ -0,0 +1,11 @@
+package asm
+
+import (
+	"github.com/oldv/mapmap/demo/domain"
+	"github.com/oldv/mapmap/demo/dto"
+)
+
+// mapmap:assembler uses:"UserAssembler" unmappedTarget:"error"
+type OrderAssembler interface {
+	ToOrderDTO(order domain.Order) dto.OrderDTO
+}
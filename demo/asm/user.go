@@ -5,11 +5,20 @@ import (
 	"github.com/oldv/mapmap/demo/dto"
 )
 
-// mapmap:assembler
+// mapmap:assembler config:"BaseConfig" unmappedTarget:"error"
 type UserAssembler interface {
-	// mapmap:source:Name,target:Age
-	// mapmap:source:Age,target:Name
+	// mapmap:source:Name,target:Name,default:"anonymous"
+	// mapmap:source:Nickname,target:NickName
 	ToAddDTO(user domain.User) (dto.UserAddDTO, error)
 
+	// mapmap:inverse:"ToAddDTO"
+	// mapmap:unmappedSource:"error",ignoreSource:"Remark"
 	ToAddUser(addDTO dto.UserAddDTO) (domain.User, error)
+
+	// mapmap:inherit:"ToAddDTO"
+	ToSummaryDTO(user domain.User) dto.UserSummaryDTO
+
+	// mapmap:nullValue:"skip",unmappedTarget:"ignore"
+	// mapmap:source:NickName,target:Nickname
+	ApplyPatch(patch dto.UserPatchDTO, user *domain.User)
 }
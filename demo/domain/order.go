@@ -0,0 +1,8 @@
+package domain
+
+type Order struct {
+	ID      int64
+	Buyer   User
+	Members []User
+	Owner   *User
+}
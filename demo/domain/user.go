@@ -1,6 +1,11 @@
 package domain
 
+import "time"
+
 type User struct {
-	Name string
-	Age  int
+	UserID    int64
+	Name      string
+	Age       int
+	Nickname  string
+	CreatedAt time.Time
 }
@@ -0,0 +1,8 @@
+package dto
+
+type OrderDTO struct {
+	ID      int64
+	Buyer   UserSummaryDTO
+	Members []UserSummaryDTO
+	Owner   *UserSummaryDTO
+}
@@ -1,6 +1,21 @@
 package dto
 
 type UserAddDTO struct {
-	Name string
-	Age  int
+	UserId    int64
+	Name      string
+	Age       int
+	NickName  string
+	CreatedAt string `mapmap:"format:2006-01-02"`
+	Remark    string `mapmap:"default:none"`
+}
+
+type UserSummaryDTO struct {
+	Name     string
+	NickName string
+}
+
+type UserPatchDTO struct {
+	Name     *string
+	Age      *int
+	NickName *string
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/oldv/mapmap/src"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func main() {
@@ -36,6 +37,9 @@ func printUsage() {
 	fmt.Println("    -f      指定文件")
 	fmt.Println("    -d      指定目录")
 	fmt.Println("    -o      输出目录(必填)")
+	fmt.Println("    -tags   逗号分隔的构建标签")
+	fmt.Println("    -goos   目标操作系统, 默认为当前环境")
+	fmt.Println("    -goarch 目标架构, 默认为当前环境")
 	fmt.Println("  help      显示此帮助信息")
 }
 
@@ -48,6 +52,9 @@ func generateCmd() {
 	filePath := genCmd.String("f", "", "指定文件路径")
 	dirPath := genCmd.String("d", "", "指定目录路径")
 	outputDir := genCmd.String("o", "", "输出目录(必填)")
+	tags := genCmd.String("tags", "", "逗号分隔的构建标签")
+	goos := genCmd.String("goos", "", "目标操作系统, 默认为当前环境")
+	goarch := genCmd.String("goarch", "", "目标架构, 默认为当前环境")
 
 	// 解析参数，注意要跳过子命令本身
 	genCmd.Parse(os.Args[2:])
@@ -76,16 +83,23 @@ func generateCmd() {
 		os.Exit(1)
 	}
 
-	// 开始扫描和生成代码
+	// 开始扫描和生成代码, 所有接口共用一个包加载器
+	buildOptions := src.BuildOptions{GOOS: *goos, GOARCH: *goarch}
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			buildOptions.Tags = append(buildOptions.Tags, tag)
+		}
+	}
+	loader := src.NewLoader(buildOptions)
 	if *filePath != "" {
-		processFile(*filePath, *outputDir)
+		processFile(loader, *filePath, *outputDir)
 	} else {
-		processDirectory(*dirPath, *outputDir)
+		processDirectory(loader, *dirPath, *outputDir)
 	}
 }
 
 // 处理单个文件
-func processFile(filePath string, outputDir string) {
+func processFile(loader *src.Loader, filePath string, outputDir string) {
 	// 检查文件是否存在
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		fmt.Printf("错误: 文件不存在: %s\n", filePath)
@@ -94,20 +108,31 @@ func processFile(filePath string, outputDir string) {
 
 	fmt.Printf("处理文件: %s\n", filePath)
 
+	// 不满足构建约束的文件不参与生成
+	if match, err := loader.MatchFile(filePath); err != nil {
+		fmt.Printf("解析文件失败: %v\n", err)
+		os.Exit(1)
+	} else if !match {
+		fmt.Printf("跳过文件: %s 不满足构建约束\n", filePath)
+		return
+	}
+
 	// 解析文件
-	interfaces, err := src.ParseFile(filePath)
+	interfaces, err := src.ParseFile(loader, filePath)
 	if err != nil {
-		fmt.Printf("解析文件失败: %v\n", err)
+		printError("解析文件失败", err)
 		os.Exit(1)
 	}
 
 	// 处理找到的接口
+	failed := false
 	for _, iface := range interfaces {
 		fmt.Printf("找到接口: %s 在包 %s 中\n", iface.Name, iface.PackageName)
 
 		// 生成转换代码
-		if err := src.ProcessInterface(iface, outputDir); err != nil {
-			fmt.Printf("处理接口 %s 失败: %v\n", iface.Name, err)
+		if err := src.ProcessInterface(loader, iface, outputDir); err != nil {
+			printError("处理接口 "+iface.Name+" 失败", err)
+			failed = true
 			continue
 		}
 
@@ -127,10 +152,15 @@ func processFile(filePath string, outputDir string) {
 			fmt.Println()
 		}
 	}
+
+	// 有文件解析失败或接口生成失败时返回非零状态码
+	if failed {
+		os.Exit(1)
+	}
 }
 
 // 处理目录
-func processDirectory(dirPath string, outputDir string) {
+func processDirectory(loader *src.Loader, dirPath string, outputDir string) {
 	// 检查目录是否存在
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		fmt.Printf("错误: 目录不存在: %s\n", dirPath)
@@ -140,17 +170,27 @@ func processDirectory(dirPath string, outputDir string) {
 	fmt.Printf("处理目录: %s\n", dirPath)
 
 	// 遍历目录中的所有 .go 文件
+	failed := false
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() && filepath.Ext(path) == ".go" {
+			// 跳过不满足构建约束的文件, 避免按平台区分的同名接口重复生成
+			if match, err := loader.MatchFile(path); err != nil {
+				printError("解析文件失败 "+path, err)
+				failed = true
+				return nil
+			} else if !match {
+				return nil
+			}
 			fmt.Printf("扫描文件: %s\n", path)
 
 			// 解析文件
-			interfaces, err := src.ParseFile(path)
+			interfaces, err := src.ParseFile(loader, path)
 			if err != nil {
-				fmt.Printf("解析文件失败 %s: %v\n", path, err)
+				printError("解析文件失败 "+path, err)
+				failed = true
 				return nil // 继续处理其他文件
 			}
 
@@ -159,8 +199,9 @@ func processDirectory(dirPath string, outputDir string) {
 				fmt.Printf("找到接口: %s 在包 %s 中\n", iface.Name, iface.PackageName)
 
 				// 生成转换代码
-				if err := src.ProcessInterface(iface, outputDir); err != nil {
-					fmt.Printf("处理接口 %s 失败: %v\n", iface.Name, err)
+				if err := src.ProcessInterface(loader, iface, outputDir); err != nil {
+					printError("处理接口 "+iface.Name+" 失败", err)
+					failed = true
 					continue
 				}
 			}
@@ -172,4 +213,14 @@ func processDirectory(dirPath string, outputDir string) {
 		fmt.Printf("错误: 遍历目录失败: %v\n", err)
 		os.Exit(1)
 	}
+
+	// 有文件解析失败或接口生成失败时返回非零状态码
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// 输出错误, 错误另起一行, 带位置的错误以 file:line:col: 开头, 便于编辑器跳转
+func printError(summary string, err error) {
+	fmt.Printf("%s:\n%v\n", summary, err)
 }
@@ -0,0 +1,50 @@
+package src
+
+import (
+	"go/build"
+	"path/filepath"
+	"strings"
+)
+
+// BuildOptions decides which files take part in the build when scanning and
+// loading, like the flags of the same name of go build
+type BuildOptions struct {
+	Tags   []string // additional build tags
+	GOOS   string   // target operating system, the current one when empty
+	GOARCH string   // target architecture, the current one when empty
+}
+
+// withDefaults fills in the operating system and architecture left unset
+func (opts BuildOptions) withDefaults() BuildOptions {
+	if opts.GOOS == "" {
+		opts.GOOS = build.Default.GOOS
+	}
+	if opts.GOARCH == "" {
+		opts.GOARCH = build.Default.GOARCH
+	}
+	return opts
+}
+
+// context creates the go/build context of the options, with cgo disabled
+// and the gc compiler
+func (opts BuildOptions) context() build.Context {
+	ctxt := build.Default
+	ctxt.GOOS = opts.GOOS
+	ctxt.GOARCH = opts.GOARCH
+	ctxt.BuildTags = opts.Tags
+	ctxt.CgoEnabled = false
+	ctxt.Compiler = "gc"
+	return ctxt
+}
+
+// matchFile reports whether a file takes part in the build, checking the
+// _GOOS and _GOARCH suffixes of its name and its //go:build or // +build
+// constraints the way go build does
+func (opts BuildOptions) matchFile(filePath string) (bool, error) {
+	if !strings.HasSuffix(filePath, ".go") {
+		return false, nil
+	}
+
+	ctxt := opts.context()
+	return ctxt.MatchFile(filepath.Dir(filePath), filepath.Base(filePath))
+}
@@ -2,44 +2,444 @@ package src
 
 import (
 	"fmt"
+	"go/token"
+	"maps"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
-// parseMethodComment extracts source and target field names from a comment
-func parseMethodComment(methodComment string) (result map[string]string, err error) {
+// FieldRule describes how a single target field is populated
+type FieldRule struct {
+	Target     string // target field name
+	Source     string // source field name
+	Ignore     bool   // leave the target field untouched
+	Format     string // layout for time values or fmt verb for string targets
+	Default    string // literal used when the source value is zero or missing
+	Constant   string // literal assigned regardless of the source
+	Expression string // Go expression assigned to the target, src is the source value
+	Condition  string // predicate method of the source or expression guarding the assignment
+	NullValue  string // what a nil or zero source does to the target: set, skip or default
+	DeepCopy   *bool  // copy pointers, slices, maps and arrays element by element, nil follows the method
+
+	origin   string         // where a rule not written on the method itself came from
+	position token.Position // annotation or struct field declaring the rule, if known
+}
+
+// Options holds mapping options set on an assembler interface or a method,
+// keyed by option name
+type Options map[string]string
+
+// MethodConfig is everything the comments of a method declare
+type MethodConfig struct {
+	Rules   []FieldRule // field rules in declaration order
+	Options Options     // method level options
+}
+
+// mappingOptionKeys are the options accepted on both interfaces and methods
+var mappingOptionKeys = map[string]bool{
+	"match":       true,
+	"stripPrefix": true,
+	"stripSuffix": true,
+
+	"unmappedTarget": true,
+	"unmappedSource": true,
+	"ignoreSource":   true,
+	"presenceCheck":  true,
+	"nullValue":      true,
+	"deepCopy":       true,
+
+	"subtype":         true,
+	"subtypeFallback": true,
+}
+
+// assemblerOptionKeys are the options only accepted on assembler interfaces
+var assemblerOptionKeys = map[string]bool{
+	"config": true,
+	"uses":   true,
+}
+
+// methodOptionKeys are the options only accepted on methods
+var methodOptionKeys = map[string]bool{
+	"inverse":     true,
+	"inherit":     true,
+	"constructor": true,
+	"builder":     true,
+}
+
+// unmappedPolicies are the accepted values of the unmapped field policies
+var unmappedPolicies = map[string]bool{
+	"ignore": true,
+	"warn":   true,
+	"error":  true,
+}
+
+// nullValueStrategies are the accepted values of the nullValue option
+var nullValueStrategies = map[string]bool{
+	"set":     true,
+	"skip":    true,
+	"default": true,
+}
+
+// subtypeFallbacks are the accepted values of the subtypeFallback option
+var subtypeFallbacks = map[string]bool{
+	"zero":  true,
+	"error": true,
+	"panic": true,
+}
+
+// merge returns a copy of o with the options of override applied on top
+func (o Options) merge(override Options) Options {
+	merged := make(Options, len(o)+len(override))
+	maps.Copy(merged, o)
+	maps.Copy(merged, override)
+	return merged
+}
+
+// list splits a comma separated option value
+func (o Options) list(key string) []string {
+	var values []string
+	for value := range strings.SplitSeq(o[key], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// flag reads a boolean option, a key present without a value is true
+func (o Options) flag(key string) (bool, error) {
+	value, ok := o[key]
+	if !ok {
+		return false, nil
+	}
+
+	enabled, err := parseBoolItem(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q", key, value)
+	}
+	return enabled, nil
+}
+
+// parseAssemblerComment reads the options following mapmap:assembler,
+// written in struct tag syntax: mapmap:assembler match:"snake" stripPrefix:"F"
+func parseAssemblerComment(comment string) (Options, error) {
+	index := strings.Index(comment, "mapmap:assembler")
+	if index == -1 {
+		return nil, fmt.Errorf("comment does not contain mapmap:assembler")
+	}
+
+	options, err := parseTagOptions(comment[index+len("mapmap:assembler"):])
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range options {
+		if !mappingOptionKeys[key] && !assemblerOptionKeys[key] {
+			return nil, fmt.Errorf("unknown assembler option %q", key)
+		}
+	}
+
+	return options, nil
+}
+
+// parseTagOptions parses key:"value" pairs separated by spaces, following
+// the reflect.StructTag conventions. A key without a value is a flag.
+func parseTagOptions(tag string) (Options, error) {
+	options := make(Options)
+
+	for {
+		tag = strings.TrimLeft(tag, " \t")
+		if tag == "" {
+			return options, nil
+		}
+
+		// scan to colon, space or end, a key may not contain quotes or control characters
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("invalid option syntax near %q", tag)
+		}
+		key := tag[:i]
+		tag = tag[i:]
+
+		if !strings.HasPrefix(tag, ":") {
+			options[key] = ""
+			continue
+		}
+		if len(tag) < 2 || tag[1] != '"' {
+			return nil, fmt.Errorf("value of option %q must be quoted", key)
+		}
+
+		// scan quoted string to find value
+		i = 2
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, fmt.Errorf("unterminated value of option %q", key)
+		}
+
+		value, err := strconv.Unquote(tag[1 : i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of option %q: %v", key, err)
+		}
+		options[key] = value
+		tag = tag[i+1:]
+	}
+}
+
+// isMapmapComment reports whether a comment line carries a mapmap annotation
+func isMapmapComment(comment string) bool {
+	comment = strings.TrimSpace(strings.Trim(comment, "/"))
+	return strings.HasPrefix(comment, "mapmap:")
+}
+
+// parseMethodComments collects the rules and options of all comment lines of a method
+func parseMethodComments(method MethodInfo) (*MethodConfig, error) {
+	config := &MethodConfig{Options: make(Options)}
+
+	for i, comment := range method.Comment {
+		if !isMapmapComment(comment) {
+			continue
+		}
+
+		var position token.Position
+		if i < len(method.CommentPositions) {
+			position = method.CommentPositions[i]
+		}
+		rules, options, err := parseMethodComment(comment, position)
+		if err != nil {
+			return nil, err
+		}
+		config.Rules = append(config.Rules, rules...)
+		maps.Copy(config.Options, options)
+	}
+
+	return config, nil
+}
+
+// parseMethodComment extracts field rules and method options from a comment
+// starting at position. A mapmap: entry with a target is a field rule, any
+// other entry sets options. Errors point at the offending entry.
+func parseMethodComment(methodComment string, position token.Position) (rules []FieldRule, options Options, err error) {
 	// one line comment may be have multiple mapmap:
-	methodComment = strings.Trim(methodComment, "/")
-	methodComment = strings.Trim(methodComment, " ")
-	if !strings.HasPrefix(methodComment, "mapmap:") {
-		return result, fmt.Errorf("method comment does not contain mapmap")
+	text := strings.TrimSpace(strings.Trim(methodComment, "/"))
+	if !strings.HasPrefix(text, "mapmap:") {
+		return nil, nil, errorAt(position, "method comment does not contain mapmap")
 	}
 
-	result = make(map[string]string)
+	options = make(Options)
+
+	// first split by "mapmap:" get target map source items, tracking where
+	// each entry starts in the comment
+	offset := strings.Index(methodComment, text)
+	for i, part := range strings.Split(text, "mapmap:") {
+		entryPosition := shiftPosition(position, offset)
+		if i > 0 {
+			offset += len("mapmap:")
+		}
+		offset += len(part)
 
-	// first split by "mapmap:" get target map source items
-	parts := strings.SplitSeq(methodComment, "mapmap:")
-	for part := range parts {
+		part = strings.TrimSpace(part)
 		if part == "" {
 			continue
 		}
 
-		targetAndSource := strings.Split(part, ",")
+		items := splitRuleItems(part)
+		if !isFieldRule(items) {
+			for _, item := range items {
+				key, value := splitRuleItem(item)
+				if !mappingOptionKeys[key] && !methodOptionKeys[key] {
+					return nil, nil, errorAt(entryPosition, "unknown method option %q", key)
+				}
+				options[key] = value
+			}
+			continue
+		}
 
-		var sourceField, targetField string
-		for _, targetOrSource := range targetAndSource {
-			targetOrSource = strings.TrimSpace(targetOrSource)
-			if strings.HasPrefix(targetOrSource, "source:") {
-				sourceField = strings.TrimPrefix(targetOrSource, "source:")
-			} else if strings.HasPrefix(targetOrSource, "target:") {
-				targetField = strings.TrimPrefix(targetOrSource, "target:")
+		var rule FieldRule
+		for _, item := range items {
+			key, value := splitRuleItem(item)
+			if err := applyRuleItem(&rule, key, value); err != nil {
+				return nil, nil, atPosition(entryPosition, err)
 			}
 		}
 
-		fmt.Println(targetField)
-		fmt.Println(sourceField)
+		if rule.Target == "" {
+			return nil, nil, errorAt(entryPosition, "mapping rule %q does not specify a target", part)
+		}
+		if rule.valueSources() > 1 {
+			return nil, nil, errorAt(entryPosition, "mapping rule %q may only use one of source, constant and expression", part)
+		}
+		rule.position = entryPosition
+
+		rules = append(rules, rule)
+	}
+
+	return rules, options, nil
+}
+
+// valueSources counts how many of source, constant and expression a rule sets
+func (r FieldRule) valueSources() int {
+	count := 0
+	for _, value := range []string{r.Source, r.Constant, r.Expression} {
+		if value != "" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// isFieldRule reports whether the items of a mapmap: entry describe a field rule
+func isFieldRule(items []string) bool {
+	for _, item := range items {
+		key, _ := splitRuleItem(item)
+		if key == "target" || key == "source" {
+			return true
+		}
+	}
+
+	return false
+}
 
-		result[targetField] = sourceField
+// applyRuleItem sets a single key of a field rule
+func applyRuleItem(rule *FieldRule, key, value string) error {
+	switch key {
+	case "source":
+		rule.Source = value
+	case "target":
+		rule.Target = value
+	case "ignore":
+		ignore, err := parseBoolItem(value)
+		if err != nil {
+			return fmt.Errorf("invalid ignore value %q", value)
+		}
+		rule.Ignore = ignore
+	case "format":
+		rule.Format = value
+	case "default":
+		rule.Default = value
+	case "constant":
+		rule.Constant = value
+	case "expression":
+		rule.Expression = value
+	case "condition":
+		rule.Condition = value
+	case "nullValue":
+		if !nullValueStrategies[value] {
+			return fmt.Errorf("invalid nullValue %q, expected set, skip or default", value)
+		}
+		rule.NullValue = value
+	case "deepCopy":
+		deepCopy, err := parseBoolItem(value)
+		if err != nil {
+			return fmt.Errorf("invalid deepCopy value %q", value)
+		}
+		rule.DeepCopy = &deepCopy
+	default:
+		return fmt.Errorf("unknown mapping option %q", key)
+	}
+
+	return nil
+}
+
+// parseBoolItem treats a bare flag as true
+func parseBoolItem(value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// splitRuleItems splits a rule on commas that are not inside double quotes
+func splitRuleItems(rule string) []string {
+	var items []string
+	var current strings.Builder
+	inQuote := false
+
+	for i := 0; i < len(rule); i++ {
+		c := rule[i]
+		switch {
+		case c == '\\' && inQuote && i+1 < len(rule):
+			current.WriteByte(c)
+			i++
+			current.WriteByte(rule[i])
+			continue
+		case c == '"':
+			inQuote = !inQuote
+		case c == ',' && !inQuote:
+			if item := strings.TrimSpace(current.String()); item != "" {
+				items = append(items, item)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+
+	if item := strings.TrimSpace(current.String()); item != "" {
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// splitRuleItem splits "key:value" on the first colon and unquotes the value
+func splitRuleItem(item string) (key, value string) {
+	key, value, _ = strings.Cut(item, ":")
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	return key, value
+}
+
+// parseFieldTag reads the mapsource and mapmap struct tags of a field.
+// On a target field "name" is the source field to read from, on a source
+// field it is the target field to write to. mapsource names a source field;
+// it is ignored when the struct is the source, as in the inverse method.
+func parseFieldTag(fieldName string, tag string, onSource bool) (rule FieldRule, ok bool, err error) {
+	structTag := reflect.StructTag(tag)
+
+	if mapSource, found := structTag.Lookup("mapsource"); found && mapSource != "" && !onSource {
+		rule.Source = mapSource
+		ok = true
+	}
+
+	mapmapTag, found := structTag.Lookup("mapmap")
+	if !found {
+		return rule, ok, nil
+	}
+
+	if mapmapTag == "-" {
+		rule.Ignore = true
+		return rule, true, nil
+	}
+
+	for _, item := range splitRuleItems(mapmapTag) {
+		key, value := splitRuleItem(item)
+		switch key {
+		case "name":
+			rule.Source = value
+		case "source", "target":
+			return rule, false, fmt.Errorf("field %s: use name instead of %s in mapmap tag", fieldName, key)
+		default:
+			if err := applyRuleItem(&rule, key, value); err != nil {
+				return rule, false, fmt.Errorf("field %s: %v", fieldName, err)
+			}
+		}
 	}
 
-	return result, nil
+	return rule, true, nil
 }
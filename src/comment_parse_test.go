@@ -0,0 +1,310 @@
+package src
+
+import (
+	"fmt"
+	"go/token"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTagOptions(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    Options
+		wantErr string
+	}{
+		{tag: "", want: Options{}},
+		{tag: `match:"snake"`, want: Options{"match": "snake"}},
+		{tag: ` match:"snake"  stripPrefix:"F" `, want: Options{"match": "snake", "stripPrefix": "F"}},
+		{tag: `deepCopy presenceCheck:"IsSet"`, want: Options{"deepCopy": "", "presenceCheck": "IsSet"}},
+		{tag: `uses:"UserAssembler,common.TimeAssembler"`, want: Options{"uses": "UserAssembler,common.TimeAssembler"}},
+		{tag: `subtype:"Cat:dto.CatDTO" nullValue:"skip"`, want: Options{"subtype": "Cat:dto.CatDTO", "nullValue": "skip"}},
+		{tag: `format:"say \"hi\""`, want: Options{"format": `say "hi"`}},
+		{tag: `match:snake`, wantErr: `value of option "match" must be quoted`},
+		{tag: `match:"snake`, wantErr: `unterminated value of option "match"`},
+		{tag: `"match"`, wantErr: "invalid option syntax"},
+		{tag: `:"snake"`, wantErr: "invalid option syntax"},
+		{tag: `format:"\q"`, wantErr: `invalid value of option "format"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := parseTagOptions(tt.tag)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseTagOptions(%q) = %v, %v, want error %q", tt.tag, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTagOptions(%q) failed: %v", tt.tag, err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseTagOptions(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAssemblerComment(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    Options
+		wantErr string
+	}{
+		{comment: "// mapmap:assembler", want: Options{}},
+		{comment: `// mapmap:assembler match:"snake" config:"common.BaseConfig"`, want: Options{"match": "snake", "config": "common.BaseConfig"}},
+		{comment: `// mapmap:assembler uses:"UserAssembler"`, want: Options{"uses": "UserAssembler"}},
+		{comment: `// mapmap:assembler inverse:"ToDTO"`, wantErr: `unknown assembler option "inverse"`},
+		{comment: `// mapmap:assembler match:snake`, wantErr: "must be quoted"},
+		{comment: "// mapmap:config", wantErr: "does not contain mapmap:assembler"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			got, err := parseAssemblerComment(tt.comment)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseAssemblerComment(%q) = %v, %v, want error %q", tt.comment, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAssemblerComment(%q) failed: %v", tt.comment, err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseAssemblerComment(%q) = %v, want %v", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	options := Options{"uses": " A, b.B ,,C ", "deepCopy": "", "presenceCheck": "false", "broken": "maybe"}
+
+	if got, want := options.list("uses"), []string{"A", "b.B", "C"}; !slices.Equal(got, want) {
+		t.Errorf("list(uses) = %v, want %v", got, want)
+	}
+	if got := options.list("missing"); got != nil {
+		t.Errorf("list(missing) = %v, want nil", got)
+	}
+
+	flags := []struct {
+		key     string
+		want    bool
+		wantErr bool
+	}{
+		{key: "deepCopy", want: true},
+		{key: "presenceCheck", want: false},
+		{key: "missing", want: false},
+		{key: "broken", wantErr: true},
+	}
+	for _, tt := range flags {
+		got, err := options.flag(tt.key)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("flag(%s) = %v, %v, want %v, error %v", tt.key, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	merged := Options{"match": "snake", "nullValue": "set"}.merge(Options{"nullValue": "skip"})
+	if want := (Options{"match": "snake", "nullValue": "skip"}); !maps.Equal(merged, want) {
+		t.Errorf("merge = %v, want %v", merged, want)
+	}
+}
+
+func TestSplitRuleItems(t *testing.T) {
+	tests := []struct {
+		rule string
+		want []string
+	}{
+		{rule: "", want: nil},
+		{rule: "source:Name,target:NickName", want: []string{"source:Name", "target:NickName"}},
+		{rule: " source:Name , , target:NickName ,", want: []string{"source:Name", "target:NickName"}},
+		{rule: `target:Age,expression:"max(src.Age, 0)"`, want: []string{"target:Age", `expression:"max(src.Age, 0)"`}},
+		{rule: `target:Tag,constant:"a\",b"`, want: []string{"target:Tag", `constant:"a\",b"`}},
+		{rule: `target:Path,constant:"C:\\dir,x"`, want: []string{"target:Path", `constant:"C:\\dir,x"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			if got := splitRuleItems(tt.rule); !slices.Equal(got, tt.want) {
+				t.Errorf("splitRuleItems(%q) = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitRuleItem(t *testing.T) {
+	tests := []struct {
+		item, key, value string
+	}{
+		{item: "source:Name", key: "source", value: "Name"},
+		{item: " target : NickName ", key: "target", value: "NickName"},
+		{item: `format:"2006-01-02 15:04:05"`, key: "format", value: "2006-01-02 15:04:05"},
+		{item: `expression:"strings.ToUpper(src.Name)"`, key: "expression", value: "strings.ToUpper(src.Name)"},
+		{item: "ignore", key: "ignore", value: ""},
+		{item: `constant:"unterminated`, key: "constant", value: `"unterminated`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.item, func(t *testing.T) {
+			key, value := splitRuleItem(tt.item)
+			if key != tt.key || value != tt.value {
+				t.Errorf("splitRuleItem(%q) = %q, %q, want %q, %q", tt.item, key, value, tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestParseFieldTag(t *testing.T) {
+	deepCopy := true
+	tests := []struct {
+		name     string
+		tag      string
+		onSource bool
+		want     FieldRule
+		wantOK   bool
+		wantErr  string
+	}{
+		{name: "no tag", tag: ""},
+		{name: "other tags", tag: `json:"name"`},
+		{name: "mapsource", tag: `mapsource:"Nickname"`, want: FieldRule{Source: "Nickname"}, wantOK: true},
+		{name: "empty mapsource", tag: `mapsource:""`},
+		{name: "mapsource on source", tag: `mapsource:"Nickname"`, onSource: true},
+		{name: "name", tag: `mapmap:"name:Name"`, want: FieldRule{Source: "Name"}, wantOK: true},
+		{name: "name on source", tag: `mapmap:"name:FullName"`, onSource: true, want: FieldRule{Source: "FullName"}, wantOK: true},
+		{name: "format", tag: `mapmap:"format:2006-01-02"`, want: FieldRule{Format: "2006-01-02"}, wantOK: true},
+		{name: "default", tag: `mapmap:"default:none"`, want: FieldRule{Default: "none"}, wantOK: true},
+		{name: "ignore", tag: `mapmap:"-"`, want: FieldRule{Ignore: true}, wantOK: true},
+		{name: "ignore wins over mapsource", tag: `mapsource:"Nickname" mapmap:"-"`, want: FieldRule{Source: "Nickname", Ignore: true}, wantOK: true},
+		{name: "mapsource with options", tag: `mapsource:"Created" mapmap:"format:2006-01-02,nullValue:skip"`,
+			want: FieldRule{Source: "Created", Format: "2006-01-02", NullValue: "skip"}, wantOK: true},
+		{name: "deepCopy", tag: `mapmap:"deepCopy"`, want: FieldRule{DeepCopy: &deepCopy}, wantOK: true},
+		{name: "quoted value", tag: `mapmap:"default:\"a,b\""`, want: FieldRule{Default: "a,b"}, wantOK: true},
+		{name: "source key", tag: `mapmap:"source:Name"`, wantErr: "field Title: use name instead of source in mapmap tag"},
+		{name: "target key", tag: `mapmap:"target:Name"`, wantErr: "use name instead of target"},
+		{name: "unknown key", tag: `mapmap:"colour:red"`, wantErr: `field Title: unknown mapping option "colour"`},
+		{name: "invalid nullValue", tag: `mapmap:"nullValue:maybe"`, wantErr: `invalid nullValue "maybe"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := parseFieldTag("Title", tt.tag, tt.onSource)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseFieldTag(%q) error = %v, want %q", tt.tag, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFieldTag(%q) failed: %v", tt.tag, err)
+			}
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFieldTag(%q) = %+v, %v, want %+v, %v", tt.tag, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseMethodComment(t *testing.T) {
+	position := token.Position{Filename: "user.go", Line: 12, Column: 2}
+	tests := []struct {
+		name        string
+		comment     string
+		wantRules   []FieldRule
+		wantOptions Options
+		wantErr     string
+		wantColumn  int // column of the error, 0 when not checked
+	}{
+		{
+			name:        "single rule",
+			comment:     "// mapmap:source:Nickname,target:NickName",
+			wantRules:   []FieldRule{{Source: "Nickname", Target: "NickName"}},
+			wantOptions: Options{},
+		},
+		{
+			name:        "rules and options on one line",
+			comment:     `// mapmap:target:Age,default:"18" mapmap:ignoreSource:"Password" mapmap:target:ID,ignore`,
+			wantRules:   []FieldRule{{Target: "Age", Default: "18"}, {Target: "ID", Ignore: true}},
+			wantOptions: Options{"ignoreSource": "Password"},
+		},
+		{
+			name:        "expression with commas",
+			comment:     `// mapmap:target:Name,expression:"strings.Join([]string{src.First, src.Last}, \" \")"`,
+			wantRules:   []FieldRule{{Target: "Name", Expression: `strings.Join([]string{src.First, src.Last}, " ")`}},
+			wantOptions: Options{},
+		},
+		{
+			name:        "condition and nullValue",
+			comment:     `// mapmap:source:Email,target:Email,condition:HasEmail,nullValue:skip`,
+			wantRules:   []FieldRule{{Source: "Email", Target: "Email", Condition: "HasEmail", NullValue: "skip"}},
+			wantOptions: Options{},
+		},
+		{
+			name:        "method options",
+			comment:     `// mapmap:inverse:"ToAddDTO",unmappedTarget:"error"`,
+			wantOptions: Options{"inverse": "ToAddDTO", "unmappedTarget": "error"},
+		},
+		{
+			name:    "not a mapmap comment",
+			comment: "// converts a user",
+			wantErr: "does not contain mapmap",
+		},
+		{
+			name:       "unknown option",
+			comment:    "// mapmap:target:Name mapmap:colour:red",
+			wantErr:    `unknown method option "colour"`,
+			wantColumn: 24,
+		},
+		{
+			name:       "rule without target",
+			comment:    "// mapmap:source:Name",
+			wantErr:    "does not specify a target",
+			wantColumn: 5,
+		},
+		{
+			name:    "several value sources",
+			comment: `// mapmap:source:Name,target:Name,constant:"x"`,
+			wantErr: "may only use one of source, constant and expression",
+		},
+		{
+			name:    "invalid ignore",
+			comment: "// mapmap:target:Name,ignore:maybe",
+			wantErr: `invalid ignore value "maybe"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, options, err := parseMethodComment(tt.comment, position)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseMethodComment(%q) error = %v, want %q", tt.comment, err, tt.wantErr)
+				}
+				if tt.wantColumn != 0 && !strings.HasPrefix(err.Error(), fmt.Sprintf("user.go:12:%d:", tt.wantColumn)) {
+					t.Errorf("error %q does not point at column %d", err, tt.wantColumn)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMethodComment(%q) failed: %v", tt.comment, err)
+			}
+
+			for i := range rules {
+				if rules[i].position.Line != position.Line {
+					t.Errorf("rule %d is at %v, want line %d", i, rules[i].position, position.Line)
+				}
+				rules[i].position = token.Position{}
+			}
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Errorf("rules = %+v, want %+v", rules, tt.wantRules)
+			}
+			if !maps.Equal(options, tt.wantOptions) {
+				t.Errorf("options = %v, want %v", options, tt.wantOptions)
+			}
+		})
+	}
+}
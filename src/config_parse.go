@@ -0,0 +1,267 @@
+package src
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TypeRef is a type referenced by name, such as the value of the config and
+// uses options
+type TypeRef struct {
+	Name        string // type name
+	PackagePath string // import path of the package declaring the type
+	PackageName string // name of the package declaring the type
+}
+
+// ConfigInfo is a shared config, an interface or struct declared with a
+// mapmap:config comment
+type ConfigInfo struct {
+	TypeRef
+	Options Options // options declared on mapmap:config
+}
+
+// converter is a conversion method of a config or a used assembler, shaped
+// func(S) T or func(S) (T, error), optionally taking a context.Context before
+// the source
+type converter struct {
+	receiver     string     // expression the method is called on, such as a.BaseConfig
+	name         string     // method name
+	source       types.Type // parameter type
+	target       types.Type // result type
+	returnsError bool       // the method returns an error
+	takesContext bool       // the first parameter is a context.Context
+
+	owner *EmbeddedAssembler // the embedded assembler declaring it, which alone uses it; nil for all methods
+}
+
+// resolveTypeRef resolves a type referenced in the comments of an interface,
+// written BaseConfig in the same package or common.BaseConfig in another,
+// finding its package through the modules cached by the Loader. It returns
+// the reference and the directory of the package declaring the type.
+func (l *Loader) resolveTypeRef(file *ast.File, filePath string, ref string) (TypeRef, string, error) {
+	dir := filepath.Dir(filePath)
+	resolver, err := l.resolverFor(dir)
+	if err != nil {
+		return TypeRef{}, "", err
+	}
+
+	qualifier, name, qualified := strings.Cut(ref, ".")
+	if !qualified {
+		importPath, err := resolver.importPathOf(dir)
+		if err != nil {
+			return TypeRef{}, "", err
+		}
+		return TypeRef{Name: qualifier, PackagePath: importPath, PackageName: file.Name.Name}, dir, nil
+	}
+
+	// find the package by its import name, or the name it declares when the import has none
+	for _, spec := range file.Imports {
+		pkgPath := strings.Trim(spec.Path.Value, "\"")
+		if spec.Name != nil && spec.Name.Name != qualifier {
+			continue
+		}
+
+		pkgDir, err := resolver.packageDir(pkgPath, dir)
+		if err != nil {
+			if spec.Name == nil {
+				continue
+			}
+			return TypeRef{}, "", fmt.Errorf("failed to find package %s: %v", pkgPath, err)
+		}
+		if spec.Name == nil {
+			if pkgName, err := l.packageNameOf(pkgDir); err != nil || pkgName != qualifier {
+				continue
+			}
+		}
+
+		return TypeRef{Name: name, PackagePath: pkgPath, PackageName: qualifier}, pkgDir, nil
+	}
+
+	return TypeRef{}, "", fmt.Errorf("package %s of type %s is not imported", qualifier, ref)
+}
+
+// resolveConfig resolves the shared config an interface refers to with the
+// config option, such as BaseConfig or common.BaseConfig
+func (l *Loader) resolveConfig(file *ast.File, filePath string, ref string) (*ConfigInfo, error) {
+	typeRef, pkgDir, err := l.resolveTypeRef(file, filePath, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// find the type declared with a mapmap:config comment in the package directory
+	config, err := l.findConfigDecl(pkgDir, typeRef.Name)
+	if err != nil {
+		return nil, err
+	}
+	config.TypeRef = typeRef
+
+	return config, nil
+}
+
+// findConfigDecl finds the declaration of a config type in the files of a
+// package directory that satisfy the build constraints
+func (l *Loader) findConfigDecl(dir string, name string) (*ConfigInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		if match, err := l.build.matchFile(filepath.Join(dir, entry.Name())); err != nil || !match {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, entry.Name()), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file: %v", err)
+		}
+
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok || typeSpec.Name.Name != name {
+					continue
+				}
+
+				// a type declared alone has its comment on the GenDecl, one in a group on the TypeSpec
+				doc := typeSpec.Doc
+				if doc == nil {
+					doc = genDecl.Doc
+				}
+
+				options, found, err := parseConfigDoc(doc)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse config %s: %v", name, err)
+				}
+				if !found {
+					return nil, fmt.Errorf("type %s has no mapmap:config comment", name)
+				}
+
+				return &ConfigInfo{Options: options}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("config %s not found in directory %s", name, dir)
+}
+
+// parseConfigDoc parses the options of a mapmap:config comment
+func parseConfigDoc(doc *ast.CommentGroup) (Options, bool, error) {
+	if doc == nil {
+		return nil, false, nil
+	}
+
+	options := make(Options)
+	found := false
+	for _, comment := range doc.List {
+		index := strings.Index(comment.Text, "mapmap:config")
+		if index == -1 {
+			continue
+		}
+		found = true
+
+		commentOptions, err := parseTagOptions(comment.Text[index+len("mapmap:config"):])
+		if err != nil {
+			return nil, false, err
+		}
+		for key, value := range commentOptions {
+			if !mappingOptionKeys[key] {
+				return nil, false, fmt.Errorf("unknown config option %q", key)
+			}
+			options[key] = value
+		}
+	}
+
+	return options, found, nil
+}
+
+// lookupTypeRef loads the type a reference names
+func lookupTypeRef(imp types.Importer, ref TypeRef) (*types.TypeName, error) {
+	pkg, err := getLocalPackageInfo(imp, ref.PackagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	typeName, ok := pkg.Scope().Lookup(ref.Name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in package %s", ref.Name, pkg.Path())
+	}
+
+	return typeName, nil
+}
+
+// methodConverters returns the methods of a config or assembler type that
+// can convert values, called on receiver in the generated code
+func methodConverters(t types.Type, receiver string) []converter {
+	// methods with value and pointer receivers can both be called through the field
+	methodSet := types.NewMethodSet(types.NewPointer(t))
+	if types.IsInterface(t) {
+		methodSet = types.NewMethodSet(t)
+	}
+
+	var converters []converter
+	for i := range methodSet.Len() {
+		method, ok := methodSet.At(i).Obj().(*types.Func)
+		if !ok || !method.Exported() {
+			continue
+		}
+
+		signature := method.Type().(*types.Signature)
+		params := signature.Params()
+		takesContext := params.Len() == 2 && isContext(params.At(0).Type())
+		if params.Len() != 1 && !takesContext || signature.Variadic() {
+			continue
+		}
+
+		conv := converter{
+			receiver:     receiver,
+			name:         method.Name(),
+			source:       params.At(params.Len() - 1).Type(),
+			takesContext: takesContext,
+		}
+		switch {
+		case signature.Results().Len() == 1:
+			conv.target = signature.Results().At(0).Type()
+		case signature.Results().Len() == 2 && isError(signature.Results().At(1).Type()):
+			conv.target = signature.Results().At(0).Type()
+			conv.returnsError = true
+		default:
+			continue
+		}
+
+		converters = append(converters, conv)
+	}
+
+	return converters
+}
+
+// isContext reports whether a type is context.Context
+func isContext(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+
+	return named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
+
+// isError reports whether a type is error
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
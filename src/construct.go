@@ -0,0 +1,372 @@
+package src
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// writeConstruction writes the statements creating the target through a
+// function of the target package instead of a composite literal:
+//
+//   - builder:"NewUserBuilder" calls the function, then WithX(value) for every
+//     target field X the builder can set, then Build()
+//   - constructor:"NewUser" calls the function with one argument per
+//     parameter, mapped from the source field with the same name
+//
+// Without either option a constructor named New<Target> is used when all of
+// its parameters can be mapped; constructor:"-" turns this off. Target fields
+// set by the function are not assigned again. It returns false when the
+// target is created as a composite literal.
+func (m *methodWriter) writeConstruction(options Options, matcher *nameMatcher, rules map[string]FieldRule, targetName *types.TypeName, targetType types.Type, targetStruct, sourceStruct *types.Struct) (bool, error) {
+	m.constructed = make(map[string]bool)
+
+	// an anonymous struct has no package to look up a constructor or builder in
+	if targetName == nil {
+		if options["builder"] != "" || options["constructor"] != "" && options["constructor"] != "-" {
+			return false, fmt.Errorf("constructor and builder need a named target type")
+		}
+		return false, nil
+	}
+	pkg := targetName.Pkg()
+
+	if builder := options["builder"]; builder != "" {
+		if m.update {
+			return false, fmt.Errorf("builder %s cannot be used by a method updating an existing target", builder)
+		}
+		return true, m.writeBuilder(builder, matcher, rules, pkg, targetType, targetStruct, sourceStruct)
+	}
+
+	name, explicit := options["constructor"], true
+	switch {
+	case name == "-":
+		return false, nil
+	case name != "" && m.update:
+		return false, fmt.Errorf("constructor %s cannot be used by a method updating an existing target", name)
+	case name == "" && m.update:
+		return false, nil
+	case name == "":
+		name, explicit = "New"+targetName.Name(), false
+	}
+
+	fn, _ := pkg.Scope().Lookup(name).(*types.Func)
+	if fn == nil {
+		if explicit {
+			return false, fmt.Errorf("constructor %s not found in package %s", name, pkg.Path())
+		}
+		return false, nil
+	}
+
+	// a detected constructor that does not fit is ignored, a requested one is an error
+	call, err := m.callFactory(fn, matcher, rules, targetStruct, sourceStruct)
+	if err == nil {
+		err = m.checkCreated(fn.Type().(*types.Signature), targetType)
+	}
+	if err != nil {
+		if explicit {
+			return false, fmt.Errorf("constructor %s: %v", name, err)
+		}
+		return false, nil
+	}
+
+	m.body.WriteString(call.statements)
+	m.markConstructed(call.inputs, rules)
+	m.writeCreated("target", call.expr, fn.Type().(*types.Signature), targetType)
+	m.body.WriteString("\n")
+	return true, nil
+}
+
+// writeBuilder writes the statements creating the target through a builder
+func (m *methodWriter) writeBuilder(name string, matcher *nameMatcher, rules map[string]FieldRule, pkg *types.Package, targetType types.Type, targetStruct, sourceStruct *types.Struct) error {
+	fn, _ := pkg.Scope().Lookup(name).(*types.Func)
+	if fn == nil {
+		return fmt.Errorf("builder %s not found in package %s", name, pkg.Path())
+	}
+
+	signature := fn.Type().(*types.Signature)
+	returnsError, err := factoryReturnsError(signature)
+	if err != nil {
+		return fmt.Errorf("builder %s: %v", name, err)
+	}
+	if returnsError && m.errReturn == "" {
+		return fmt.Errorf("builder %s returns an error but the method does not", name)
+	}
+	builderType := signature.Results().At(0).Type()
+
+	build := lookupMethod(builderType, "Build")
+	if build == nil {
+		return fmt.Errorf("builder %s has no Build method", name)
+	}
+	buildSignature := build.Type().(*types.Signature)
+	if buildSignature.Params().Len() != 0 {
+		return fmt.Errorf("Build of builder %s must not take parameters", name)
+	}
+	if err := m.checkCreated(buildSignature, targetType); err != nil {
+		return fmt.Errorf("Build of builder %s: %v", name, err)
+	}
+
+	call, err := m.callFactory(fn, matcher, rules, targetStruct, sourceStruct)
+	if err != nil {
+		return fmt.Errorf("builder %s: %v", name, err)
+	}
+	m.body.WriteString(call.statements)
+	m.markConstructed(call.inputs, rules)
+	if returnsError {
+		m.body.WriteString(fmt.Sprintf("\tbuilder, err := %s\n\tif err != nil {\n\t\t%s\n\t}\n", call.expr, m.errReturn))
+	} else {
+		m.body.WriteString(fmt.Sprintf("\tbuilder := %s\n", call.expr))
+	}
+
+	// WithX(value) sets target field X, chained by assigning the returned builder
+	for i := range targetStruct.NumFields() {
+		field := targetStruct.Field(i)
+		if m.constructed[field.Name()] {
+			continue
+		}
+		setter := lookupMethod(builderType, "With"+upperFirst(field.Name()))
+		if setter == nil {
+			continue
+		}
+		setterSignature := setter.Type().(*types.Signature)
+		if setterSignature.Params().Len() != 1 || setterSignature.Results().Len() > 1 {
+			continue
+		}
+		chained := setterSignature.Results().Len() == 1
+		if chained && !types.Identical(setterSignature.Results().At(0).Type(), builderType) {
+			continue
+		}
+
+		input, ok, err := m.factoryInput(field.Name(), matcher, rules, targetStruct, sourceStruct)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		arg, statements, err := m.factoryArg(input, field.Name(), setterSignature.Params().At(0).Type(), sourceStruct)
+		if err != nil {
+			return fmt.Errorf("builder %s: %s: %v", name, setter.Name(), err)
+		}
+		m.body.WriteString(statements)
+		m.markConstructed([]FieldRule{input}, rules)
+
+		if chained {
+			m.body.WriteString(fmt.Sprintf("\tbuilder = builder.%s(%s)\n", setter.Name(), arg))
+		} else {
+			m.body.WriteString(fmt.Sprintf("\tbuilder.%s(%s)\n", setter.Name(), arg))
+		}
+	}
+
+	m.writeCreated("target", "builder.Build()", buildSignature, targetType)
+	m.body.WriteString("\n")
+	return nil
+}
+
+// factoryCall is a rendered call of a constructor or builder function
+type factoryCall struct {
+	expr       string      // the call expression
+	statements string      // statements preparing the arguments
+	inputs     []FieldRule // rules feeding the arguments
+}
+
+// callFactory renders a call of fn with every parameter mapped from the source
+func (m *methodWriter) callFactory(fn *types.Func, matcher *nameMatcher, rules map[string]FieldRule, targetStruct, sourceStruct *types.Struct) (factoryCall, error) {
+	signature := fn.Type().(*types.Signature)
+	if signature.Variadic() {
+		return factoryCall{}, fmt.Errorf("variadic parameters are not supported")
+	}
+
+	// a leading context.Context receives the context of the method
+	first := 0
+	var args []string
+	if signature.Params().Len() > 0 && isContext(signature.Params().At(0).Type()) && m.contextName != "" {
+		first = 1
+		args = append(args, m.contextName)
+	}
+
+	// every parameter must map before anything is rendered
+	var call factoryCall
+	for i := first; i < signature.Params().Len(); i++ {
+		param := signature.Params().At(i)
+		input, ok, err := m.factoryInput(param.Name(), matcher, rules, targetStruct, sourceStruct)
+		if err != nil {
+			return factoryCall{}, err
+		}
+		if !ok {
+			return factoryCall{}, fmt.Errorf("parameter %s matches no source field", param.Name())
+		}
+		call.inputs = append(call.inputs, input)
+	}
+
+	for i, input := range call.inputs {
+		param := signature.Params().At(first + i)
+		arg, statements, err := m.factoryArg(input, param.Name(), param.Type(), sourceStruct)
+		if err != nil {
+			return factoryCall{}, fmt.Errorf("parameter %s: %v", param.Name(), err)
+		}
+		call.statements += statements
+		args = append(args, arg)
+	}
+
+	name := fn.Name()
+	if qualifier := m.g.qualifier(fn.Pkg()); qualifier != "" {
+		name = qualifier + "." + name
+	}
+	call.expr = name + "(" + strings.Join(args, ", ") + ")"
+	return call, nil
+}
+
+// factoryInput finds the rule feeding a constructor parameter or builder
+// setter: the rule of the target field with that name, ignoring case, or
+// else a source field matched the way a target field would be
+func (m *methodWriter) factoryInput(name string, matcher *nameMatcher, rules map[string]FieldRule, targetStruct, sourceStruct *types.Struct) (FieldRule, bool, error) {
+	target := name
+	for i := range targetStruct.NumFields() {
+		field := targetStruct.Field(i)
+		if !strings.EqualFold(field.Name(), name) {
+			continue
+		}
+		if rule, ok := rules[field.Name()]; ok {
+			return rule, !rule.Ignore && rule.mapped(), nil
+		}
+		target = field.Name()
+		break
+	}
+
+	sourceName, err := matcher.match(name, "", sourceStruct, nil)
+	if err != nil {
+		return FieldRule{}, false, err
+	}
+
+	// parameter names are usually lower case versions of the field names
+	for i := range sourceStruct.NumFields() {
+		if sourceName != "" {
+			break
+		}
+		if field := sourceStruct.Field(i); strings.EqualFold(field.Name(), name) && m.g.accessible(field) {
+			sourceName = field.Name()
+		}
+	}
+	if sourceName == "" {
+		return FieldRule{}, false, nil
+	}
+
+	return FieldRule{Target: target, Source: sourceName}, true, nil
+}
+
+// factoryArg renders the argument passed for one input. Plain field reads
+// are passed directly, anything else is prepared in a variable first.
+func (m *methodWriter) factoryArg(rule FieldRule, name string, paramType types.Type, sourceStruct *types.Struct) (arg string, statements string, err error) {
+	var sourceField *types.Var
+	if rule.Source != "" {
+		sourceField = findField(sourceStruct, rule.Source)
+	}
+
+	condition, err := m.fieldCondition(rule, sourceField)
+	if err != nil {
+		return "", "", err
+	}
+	plain := rule.Format == "" && rule.Default == "" && rule.Constant == "" && rule.Expression == "" && condition == ""
+	if plain && sourceField != nil && types.AssignableTo(sourceField.Type(), paramType) {
+		return m.paramName + "." + sourceField.Name(), "", nil
+	}
+
+	// the assignment is written to a scratch body and returned
+	body := m.body
+	m.body = strings.Builder{}
+	defer func() { m.body = body }()
+
+	argName := lowerFirst(name) + "Arg"
+	m.body.WriteString(fmt.Sprintf("\tvar %s %s\n", argName, m.g.typeString(paramType)))
+	if err := m.writeFieldAssignment(rule, argName, upperFirst(name), paramType, sourceField); err != nil {
+		return "", "", err
+	}
+
+	return argName, m.body.String(), nil
+}
+
+// markConstructed records the rules set through a constructor or builder so
+// their target fields are not assigned again
+func (m *methodWriter) markConstructed(inputs []FieldRule, rules map[string]FieldRule) {
+	for _, input := range inputs {
+		if _, ok := rules[input.Target]; !ok {
+			rules[input.Target] = input
+		}
+		m.constructed[input.Target] = true
+	}
+}
+
+// checkCreated checks that a constructor or Build method returns the target,
+// a pointer to it or its element, optionally followed by an error
+func (m *methodWriter) checkCreated(signature *types.Signature, targetType types.Type) error {
+	returnsError, err := factoryReturnsError(signature)
+	if err != nil {
+		return err
+	}
+	if returnsError && m.errReturn == "" {
+		return fmt.Errorf("it returns an error but the method does not")
+	}
+	if _, ok := createdAdapter(signature.Results().At(0).Type(), targetType); !ok {
+		return fmt.Errorf("it returns %s, not %s", signature.Results().At(0).Type(), targetType)
+	}
+
+	return nil
+}
+
+// writeCreated writes name := call, adapting pointers and returning early on errors
+func (m *methodWriter) writeCreated(name, call string, signature *types.Signature, targetType types.Type) {
+	returnsError, _ := factoryReturnsError(signature)
+	adapter, _ := createdAdapter(signature.Results().At(0).Type(), targetType)
+
+	created := name
+	if adapter != "" {
+		created = "created"
+	}
+	if returnsError {
+		m.body.WriteString(fmt.Sprintf("\t%s, err := %s\n\tif err != nil {\n\t\t%s\n\t}\n", created, call, m.errReturn))
+	} else {
+		m.body.WriteString(fmt.Sprintf("\t%s := %s\n", created, call))
+	}
+	if adapter != "" {
+		m.body.WriteString(fmt.Sprintf("\t%s := %s%s\n", name, adapter, created))
+	}
+}
+
+// createdAdapter returns the operator turning a created value into the target:
+// nothing, * to dereference or & to take the address
+func createdAdapter(created, targetType types.Type) (string, bool) {
+	switch {
+	case types.Identical(created, targetType):
+		return "", true
+	case types.Identical(created, types.NewPointer(targetType)):
+		return "*", true
+	}
+	if pointer, ok := types.Unalias(targetType).(*types.Pointer); ok && types.Identical(created, pointer.Elem()) {
+		return "&", true
+	}
+
+	return "", false
+}
+
+// factoryReturnsError checks that a function returns one value, optionally followed by an error
+func factoryReturnsError(signature *types.Signature) (bool, error) {
+	switch {
+	case signature.Results().Len() == 1:
+		return false, nil
+	case signature.Results().Len() == 2 && isError(signature.Results().At(1).Type()):
+		return true, nil
+	}
+
+	return false, fmt.Errorf("it must return a value, optionally followed by an error")
+}
+
+// lookupMethod finds a method of a type, including those of a pointer to it
+func lookupMethod(t types.Type, name string) *types.Func {
+	if types.IsInterface(t) {
+		obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+		method, _ := obj.(*types.Func)
+		return method
+	}
+
+	return lookupHookMethod(t, name)
+}
@@ -0,0 +1,177 @@
+package src
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// needsDeepCopy reports whether assigning a value of type t would share
+// memory with the source: pointers, slices and maps, and arrays and structs
+// containing them in fields the generated code can reach
+func (m *methodWriter) needsDeepCopy(t types.Type) bool {
+	return m.doNeedsDeepCopy(t, nil)
+}
+
+// doNeedsDeepCopy skips types already being inspected so recursive types terminate
+func (m *methodWriter) doNeedsDeepCopy(t types.Type, visiting []types.Type) bool {
+	for _, visited := range visiting {
+		if types.Identical(visited, t) {
+			return false
+		}
+	}
+	visiting = append(visiting, t)
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map:
+		return true
+	case *types.Array:
+		return m.doNeedsDeepCopy(u.Elem(), visiting)
+	case *types.Struct:
+		for i := range u.NumFields() {
+			if m.g.accessible(u.Field(i)) && m.doNeedsDeepCopy(u.Field(i).Type(), visiting) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// containsItself reports whether a named type can reach itself through the
+// fields, elements and pointers a deep copy follows
+func containsItself(t types.Type) bool {
+	if _, named := t.(*types.Named); !named {
+		return false
+	}
+
+	var visited []types.Type
+	var reaches func(current types.Type, root bool) bool
+	reaches = func(current types.Type, root bool) bool {
+		current = types.Unalias(current)
+		if _, named := current.(*types.Named); named && !root {
+			if types.Identical(current, t) {
+				return true
+			}
+			for _, seen := range visited {
+				if types.Identical(seen, current) {
+					return false
+				}
+			}
+			visited = append(visited, current)
+		}
+
+		switch u := current.Underlying().(type) {
+		case *types.Pointer:
+			return reaches(u.Elem(), false)
+		case *types.Slice:
+			return reaches(u.Elem(), false)
+		case *types.Array:
+			return reaches(u.Elem(), false)
+		case *types.Map:
+			return reaches(u.Elem(), false)
+		case *types.Struct:
+			for i := range u.NumFields() {
+				if reaches(u.Field(i).Type(), false) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return reaches(t, true)
+}
+
+// copyValue renders a deep copy of sourceExpr into targetExpr, both of type t.
+// Structs are copied as a whole and then their reachable fields are copied
+// again; unexported fields of other packages and interface values stay shared.
+// A named type containing itself is copied by a helper calling itself.
+func (m *methodWriter) copyValue(targetExpr, sourceExpr, name string, t types.Type) (string, error) {
+	t = types.Unalias(t)
+	if !m.needsDeepCopy(t) {
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, sourceExpr), nil
+	}
+	if containsItself(t) {
+		helper := m.helperFor(t, t, name, true)
+		return fmt.Sprintf("\t%s = a.%s(%s)\n", targetExpr, helper.name, callArg(sourceExpr)), nil
+	}
+
+	return m.copyExpanded(targetExpr, sourceExpr, name, t)
+}
+
+// copyExpanded renders the deep copy of one level of t, copying the values it
+// refers to with copyValue
+func (m *methodWriter) copyExpanded(targetExpr, sourceExpr, name string, t types.Type) (string, error) {
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		valueName := lowerFirst(name) + "Copy"
+		elem, err := m.copyValue(valueName, "(*"+sourceExpr+")", name, u.Elem())
+		if err != nil {
+			return "", err
+		}
+		body := fmt.Sprintf("\t\tvar %s %s\n%s\t\t%s = &%s\n", valueName, m.g.typeString(u.Elem()), elem, targetExpr, valueName)
+		return m.nilGuard(targetExpr, sourceExpr, t, body), nil
+
+	case *types.Slice:
+		body := fmt.Sprintf("\t\t%s = make(%s, len(%s))\n", targetExpr, m.g.typeString(t), sourceExpr)
+		if !m.needsDeepCopy(u.Elem()) {
+			body += fmt.Sprintf("\t\tcopy(%s, %s)\n", targetExpr, sourceExpr)
+			return m.nilGuard(targetExpr, sourceExpr, t, body), nil
+		}
+		m.loopDepth++
+		index := loopVar("i", m.loopDepth)
+		elem, err := m.copyValue(targetExpr+"["+index+"]", sourceExpr+"["+index+"]", name+"Item", u.Elem())
+		m.loopDepth--
+		if err != nil {
+			return "", err
+		}
+		body += fmt.Sprintf("\t\tfor %s := range %s {\n%s\t\t}\n", index, sourceExpr, elem)
+		return m.nilGuard(targetExpr, sourceExpr, t, body), nil
+
+	case *types.Map:
+		m.loopDepth++
+		defer func() { m.loopDepth-- }()
+		key, value := loopVar("key", m.loopDepth), loopVar("value", m.loopDepth)
+		elem := fmt.Sprintf("\t\t\t%s[%s] = %s\n", targetExpr, key, value)
+		if m.needsDeepCopy(u.Elem()) {
+			// map elements are not addressable, so they are copied through a variable
+			valueName := lowerFirst(name) + "Copy"
+			copied, err := m.copyValue(valueName, value, name+"Value", u.Elem())
+			if err != nil {
+				return "", err
+			}
+			elem = fmt.Sprintf("\t\t\tvar %s %s\n%s\t\t\t%s[%s] = %s\n", valueName, m.g.typeString(u.Elem()), copied, targetExpr, key, valueName)
+		}
+		body := fmt.Sprintf("\t\t%s = make(%s, len(%s))\n\t\tfor %s, %s := range %s {\n%s\t\t}\n",
+			targetExpr, m.g.typeString(t), sourceExpr, key, value, sourceExpr, elem)
+		return m.nilGuard(targetExpr, sourceExpr, t, body), nil
+
+	case *types.Array:
+		m.loopDepth++
+		defer func() { m.loopDepth-- }()
+		index := loopVar("i", m.loopDepth)
+		elem, err := m.copyValue(targetExpr+"["+index+"]", sourceExpr+"["+index+"]", name+"Item", u.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("\t%s = %s\n\tfor %s := range %s {\n%s\t}\n", targetExpr, sourceExpr, index, sourceExpr, elem), nil
+
+	case *types.Struct:
+		statements := fmt.Sprintf("\t%s = %s\n", targetExpr, sourceExpr)
+		for i := range u.NumFields() {
+			field := u.Field(i)
+			if !m.g.accessible(field) || !m.needsDeepCopy(field.Type()) {
+				continue
+			}
+			copied, err := m.copyValue(targetExpr+"."+field.Name(), sourceExpr+"."+field.Name(), name+field.Name(), field.Type())
+			if err != nil {
+				return "", err
+			}
+			statements += copied
+		}
+		return statements, nil
+	}
+
+	return fmt.Sprintf("\t%s = %s\n", targetExpr, sourceExpr), nil
+}
@@ -2,71 +2,220 @@ package src
 
 import (
 	"fmt"
-	"go/importer"
+	"go/ast"
+	"go/parser"
 	"go/token"
 	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-// 获取结构体信息
-// - dependencePath: 依赖路径
-// - structName: 结构体名称
-// - 返回: 结构体信息
-func getStructInfo(dependencePath string, structName string) (*types.Struct, error) {
+// Loader loads packages from source under the module rules for a whole run.
+// All interfaces share one Loader, so every package is parsed and
+// type-checked once and the loaded types compare identical.
+type Loader struct {
+	fset      *token.FileSet
+	build     BuildOptions               // decides which files take part in the build
+	resolvers map[string]*moduleResolver // module information by directory of the interface
+	packages  map[string]*types.Package  // loaded packages by package directory
+	loading   map[string]bool            // package directories being loaded, to find import cycles
+}
 
-	// 获取依赖的包
-	pkg, err := getLocalPackageInfo(dependencePath)
+// NewLoader creates the package loader of a run. The build tags and target
+// platform of opts constrain both scanning for interfaces and loading
+// dependencies.
+func NewLoader(opts BuildOptions) *Loader {
+	return &Loader{
+		fset:      token.NewFileSet(),
+		build:     opts.withDefaults(),
+		resolvers: make(map[string]*moduleResolver),
+		packages:  make(map[string]*types.Package),
+		loading:   make(map[string]bool),
+	}
+}
+
+// moduleImporter is the importer of an interface. It resolves import paths in
+// the module of the interface, the Loader caches the packages.
+type moduleImporter struct {
+	loader   *Loader
+	resolver *moduleResolver
+	dir      string // directory of the interface, its package may have type errors
+	pkgPath  string // import path of the package of the interface
+}
+
+// importerFor creates the importer of an interface, dir decides which go.mod
+// and go.work apply
+func (l *Loader) importerFor(dir string) (*moduleImporter, error) {
+	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		fmt.Println("Error getting package info:", err)
 		return nil, err
 	}
 
-	// 获取结构体信息
-	structInfo, err := doGetStructInfo(pkg, structName)
+	resolver, err := l.resolverFor(absDir)
+	if err != nil {
+		return nil, err
+	}
+	pkgPath, err := resolver.importPathOf(absDir)
 	if err != nil {
-		fmt.Println("Error getting struct info:", err)
 		return nil, err
 	}
 
-	return structInfo, nil
+	return &moduleImporter{loader: l, resolver: resolver, dir: absDir, pkgPath: pkgPath}, nil
 }
 
-// 获取包信息
-// - pkgPath: 包路径
-// - 返回: 包信息
-func getLocalPackageInfo(pkgPath string) (*types.Package, error) {
-	// 使用 ForCompiler 创建一个本地包导入器
-	imp := importer.ForCompiler(token.NewFileSet(), "source", nil)
-	// 导入包
-	pkg, err := imp.Import(pkgPath)
+// resolverFor returns the resolver of the module of a directory, reading
+// go.mod and go.work once per directory
+func (l *Loader) resolverFor(dir string) (*moduleResolver, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolver, ok := l.resolvers[absDir]; ok {
+		return resolver, nil
+	}
+	resolver, err := newModuleResolver(absDir)
+	if err != nil {
+		return nil, err
+	}
+	l.resolvers[absDir] = resolver
+	return resolver, nil
+}
+
+// Import loads a package, implementing types.Importer
+func (imp *moduleImporter) Import(importPath string) (*types.Package, error) {
+	return imp.ImportFrom(importPath, "", 0)
+}
+
+// ImportFrom loads a package imported by the package in srcDir, implementing
+// types.ImporterFrom
+func (imp *moduleImporter) ImportFrom(importPath string, srcDir string, mode types.ImportMode) (*types.Package, error) {
+	if importPath == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	dir, err := imp.resolver.packageDir(importPath, srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := imp.loader
+	if pkg, ok := l.packages[dir]; ok {
+		return pkg, nil
+	}
+	if l.loading[dir] {
+		return nil, fmt.Errorf("import cycle through package %s", importPath)
+	}
+	l.loading[dir] = true
+	defer delete(l.loading, dir)
+
+	files, err := l.parsePackage(dir)
 	if err != nil {
 		return nil, err
 	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("package %s has no Go files for the build", importPath)
+	}
+
+	conf := types.Config{Importer: imp}
+	if dir == imp.dir {
+		// the package of the interface may hold stale generated code, only its declared types are needed
+		conf.Error = func(error) {}
+	}
+	pkg, err := conf.Check(importPath, l.fset, files, nil)
+	if err != nil && dir != imp.dir {
+		return nil, fmt.Errorf("failed to check package %s: %v", importPath, err)
+	}
+
+	l.packages[dir] = pkg
 	return pkg, nil
 }
 
-// 获取结构体信息
-// - pkg: 包信息
-// - structName: 结构体名称
-// - 返回: 结构体信息
-func doGetStructInfo(pkg *types.Package, structName string) (*types.Struct, error) {
-	// 遍历包中的所有对象
-	scope := pkg.Scope()
-	for _, name := range scope.Names() {
-		obj := scope.Lookup(name)
-		if obj == nil {
+// packageName returns the name declared by the package at an import path,
+// reading its package clause without loading it
+func (imp *moduleImporter) packageName(importPath string) (string, error) {
+	if importPath == "unsafe" {
+		return "unsafe", nil
+	}
+
+	dir, err := imp.resolver.packageDir(importPath, imp.dir)
+	if err != nil {
+		return "", err
+	}
+	return imp.loader.packageNameOf(dir)
+}
+
+// localPackage loads the package of the interface
+func (imp *moduleImporter) localPackage() (*types.Package, error) {
+	return imp.ImportFrom(imp.pkgPath, imp.dir, 0)
+}
+
+// MatchFile reports whether a file takes part in the build under the build
+// tags and target platform of the Loader
+func (l *Loader) MatchFile(filePath string) (bool, error) {
+	return l.build.matchFile(filePath)
+}
+
+// parsePackage parses the non-test files of a directory that satisfy the
+// build constraints
+func (l *Loader) parsePackage(dir string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if match, err := l.build.matchFile(filepath.Join(dir, name)); err != nil || !match {
 			continue
 		}
 
-		// 检查对象是否为类型声明
-		if typeName, ok := obj.(*types.TypeName); ok {
-			// 检查类型是否为结构体
-			if structType, ok := typeName.Type().Underlying().(*types.Struct); ok {
-				if typeName.Name() == structName {
-					return structType, nil
-				}
-			}
+		file, err := parser.ParseFile(l.fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file: %v", err)
 		}
+		files = append(files, file)
 	}
 
-	return nil, fmt.Errorf("struct %s not found in package %s", structName, pkg.Path())
+	return files, nil
+}
+
+// packageNameOf reads the package name declared by the files of a directory
+// that satisfy the build constraints
+func (l *Loader) packageNameOf(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if match, err := l.build.matchFile(filepath.Join(dir, name)); err != nil || !match {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		return file.Name.Name, nil
+	}
+
+	return "", fmt.Errorf("directory %s has no Go files", dir)
+}
+
+// getLocalPackageInfo loads a package through an importer
+func getLocalPackageInfo(imp types.Importer, pkgPath string) (*types.Package, error) {
+	pkg, err := imp.Import(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
 }
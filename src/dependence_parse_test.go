@@ -0,0 +1,96 @@
+package src
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+// writeSharedModule writes a module whose assembler packages all convert
+// between the same domain and dto types
+func writeSharedModule(t testing.TB, dir string, assemblers int) {
+	t.Helper()
+	files := map[string]string{
+		"go.mod":         "module example.com/m\n\ngo 1.22\n",
+		"domain/user.go": "package domain\n\ntype User struct {\n\tName string\n\tAge  int\n}\n",
+		"dto/user.go":    "package dto\n\ntype UserDTO struct {\n\tName string\n\tAge  int\n}\n",
+	}
+	for i := range assemblers {
+		files[fmt.Sprintf("asm%d/assembler.go", i)] = fmt.Sprintf(`package asm%d
+
+import (
+	"example.com/m/domain"
+	"example.com/m/dto"
+)
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user domain.User) dto.UserDTO
+	ToUser(userDTO dto.UserDTO) domain.User
+}
+`, i)
+	}
+
+	writeFiles(t, dir, files)
+}
+
+// generateShared generates the assembler of one package of the shared module
+func generateShared(loader *Loader, dir string, i int) error {
+	asmDir := filepath.Join(dir, fmt.Sprintf("asm%d", i))
+	interfaces, err := ParseFile(loader, filepath.Join(asmDir, "assembler.go"))
+	if err != nil {
+		return err
+	}
+	for _, iface := range interfaces {
+		if err := ProcessInterface(loader, iface, asmDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestLoaderCachesPackages(t *testing.T) {
+	isolateGoEnv(t)
+	dir := t.TempDir()
+	writeSharedModule(t, dir, 3)
+
+	loader := NewLoader(BuildOptions{})
+	if err := generateShared(loader, dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, shared := range []string{"domain", "dto"} {
+		if _, ok := loader.packages[filepath.Join(dir, shared)]; !ok {
+			t.Fatalf("package %s was not loaded", shared)
+		}
+	}
+	loaded := maps.Clone(loader.packages)
+
+	// the other assemblers reuse every package already loaded, loading one
+	// again would replace it with a package whose types are not identical
+	for i := 1; i < 3; i++ {
+		if err := generateShared(loader, dir, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for pkgDir, pkg := range loaded {
+		if loader.packages[pkgDir] != pkg {
+			t.Errorf("package %s was loaded again", pkgDir)
+		}
+	}
+}
+
+func BenchmarkGenerateSharedLoader(b *testing.B) {
+	dir := b.TempDir()
+	writeSharedModule(b, dir, 10)
+	isolateGoEnv(b)
+
+	for b.Loop() {
+		loader := NewLoader(BuildOptions{})
+		for i := range 10 {
+			if err := generateShared(loader, dir, i); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+)
+
+// Diagnostic is an error at a place in the source. It prints as
+// file:line:col: message so editors can jump to the problem.
+type Diagnostic struct {
+	Position token.Position
+	Message  string
+}
+
+// Error renders the diagnostic with its position first
+func (d *Diagnostic) Error() string {
+	return d.Position.String() + ": " + d.Message
+}
+
+// errorAt reports a formatted error at a position
+func errorAt(pos token.Position, format string, args ...any) error {
+	return atPosition(pos, fmt.Errorf(format, args...))
+}
+
+// atPosition reports err at pos. Diagnostics keep their own, more precise
+// position; joined errors are placed one by one.
+func atPosition(pos token.Position, err error) error {
+	if err == nil || !pos.IsValid() {
+		return err
+	}
+
+	return eachError(err, func(err error) error {
+		if _, ok := err.(*Diagnostic); ok {
+			return err
+		}
+		return &Diagnostic{Position: pos, Message: err.Error()}
+	})
+}
+
+// withContext prefixes the message of err, keeping the position of a
+// diagnostic in front of the whole message
+func withContext(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	context := fmt.Sprintf(format, args...)
+	return eachError(err, func(err error) error {
+		if diagnostic, ok := err.(*Diagnostic); ok {
+			return &Diagnostic{Position: diagnostic.Position, Message: context + ": " + diagnostic.Message}
+		}
+		return fmt.Errorf("%s: %w", context, err)
+	})
+}
+
+// eachError applies fn to every error joined by errors.Join, or to err itself
+func eachError(err error, fn func(error) error) error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return fn(err)
+	}
+
+	var errs []error
+	for _, inner := range joined.Unwrap() {
+		errs = append(errs, fn(inner))
+	}
+	return errors.Join(errs...)
+}
+
+// shiftPosition moves a position n bytes to the right on the same line
+func shiftPosition(pos token.Position, n int) token.Position {
+	pos.Offset += n
+	pos.Column += n
+	return pos
+}
+
+// positionOf returns where a loaded object is declared
+func (g *generator) positionOf(obj types.Object) token.Position {
+	return g.importer.loader.fset.Position(obj.Pos())
+}
@@ -0,0 +1,142 @@
+package src
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// interfaceDecl is the declaration of an embedded interface
+type interfaceDecl struct {
+	fset     *token.FileSet
+	file     *ast.File
+	filePath string
+	iface    *ast.InterfaceType
+	doc      *ast.CommentGroup // doc comment of the interface declaration
+}
+
+// parseEmbeddedInterface expands an embedded interface into all of its
+// methods, including those of the interfaces it embeds in turn. The embedded
+// type is written as UserAssembler in the same package or user.UserAssembler
+// in another. assembler is the embedded assembler the embedding sits in, nil
+// directly in the generated interface, and visiting holds the interfaces
+// being expanded to find embedding cycles.
+func (l *Loader) parseEmbeddedInterface(file *ast.File, filePath string, expr ast.Expr, assembler *EmbeddedAssembler, visiting []string) ([]MethodInfo, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		// any has no methods, the methods of other predeclared types convert nothing
+		if _, ok := types.Universe.Lookup(e.Name).(*types.TypeName); ok {
+			if e.Name == "any" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("cannot embed the predeclared type %s", e.Name)
+		}
+	case *ast.SelectorExpr:
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return nil, fmt.Errorf("embedding generic interfaces is not supported")
+	default:
+		return nil, fmt.Errorf("only interface types can be embedded")
+	}
+
+	typeRef, pkgDir, err := l.resolveTypeRef(file, filePath, exprToString(expr))
+	if err != nil {
+		return nil, err
+	}
+
+	key := embedKey(pkgDir, typeRef.Name)
+	if slices.Contains(visiting, key) {
+		return nil, fmt.Errorf("interface %s embeds itself", typeRef.Name)
+	}
+	visiting = append(visiting, key)
+
+	decl, err := l.findInterfaceDecl(pkgDir, typeRef.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// the method types of an embedded interface resolve in the file declaring it
+	scope := &TypeScope{PackagePath: typeRef.PackagePath, Imports: fileImports(decl.file)}
+
+	// the options, config and uses of an embedded mapmap:assembler apply to the methods it contributes
+	embedded, err := l.parseAssemblerDoc(decl.fset, decl.file, decl.filePath, typeRef.Name, decl.doc)
+	if err != nil {
+		return nil, err
+	}
+	if embedded != nil {
+		embedded.Scope = scope
+		embedded.Outer = assembler
+		assembler = embedded
+	}
+
+	return l.parseInterfaceMethods(decl.fset, decl.file, decl.filePath, decl.iface, scope, assembler, visiting)
+}
+
+// embedKey identifies an interface in cycle detection by its package directory and name
+func embedKey(dir string, name string) string {
+	if absDir, err := filepath.Abs(dir); err == nil {
+		dir = absDir
+	}
+	return dir + "." + name
+}
+
+// findInterfaceDecl finds the declaration of an interface in the files of a
+// package directory that satisfy the build constraints
+func (l *Loader) findInterfaceDecl(dir string, name string) (*interfaceDecl, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		filePath := filepath.Join(dir, entry.Name())
+		if match, err := l.build.matchFile(filePath); err != nil || !match {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file: %v", err)
+		}
+
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok || typeSpec.Name.Name != name {
+					continue
+				}
+
+				interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
+				if !ok {
+					return nil, fmt.Errorf("%s is not an interface but %s", name, types.ExprString(typeSpec.Type))
+				}
+				if typeSpec.TypeParams != nil {
+					return nil, fmt.Errorf("embedding the generic interface %s is not supported", name)
+				}
+
+				doc := typeSpec.Doc
+				if doc == nil {
+					doc = genDecl.Doc
+				}
+				return &interfaceDecl{fset: fset, file: file, filePath: filePath, iface: interfaceType, doc: doc}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("interface %s not found in directory %s", name, dir)
+}
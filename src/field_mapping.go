@@ -0,0 +1,816 @@
+package src
+
+import (
+	"fmt"
+	"go/types"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// methodWriter accumulates the body of one generated method
+type methodWriter struct {
+	g             *generator
+	origin        MethodInfo       // interface method the statements are generated for
+	paramName     string           // name of the source parameter
+	contextName   string           // name of the context.Context parameter, empty if the method has none
+	usesSrc       bool             // an expression refers to the source as src
+	sourceType    types.Type       // type of the source parameter
+	presenceCheck bool             // guard fields with HasX() methods of the source
+	deepCopyAll   bool             // deepCopy option of the method
+	deepCopy      bool             // deep copy the field currently being assigned
+	pairStack     []typePair       // source and target types being assigned, to detect recursive types
+	update        bool             // the method fills an existing target instead of a new one
+	options       Options          // options in effect, the method options over the interface options
+	optionScope   *TypeScope       // where the subtype option is written, nil for the interface file
+	nullValue     string           // method level nullValue strategy
+	subtypes      []subtypeMapping // concrete types of interface values and what they map to
+	subFallback   string           // what an interface value of an unlisted concrete type becomes
+	loopDepth     int              // nesting of generated element loops
+	fieldTarget   string           // target field currently being assigned
+	resetOnNil    bool             // clear fieldTarget when its source is nil
+	omitNilGuard  bool             // the assignment of fieldTarget is already guarded by a zero value check
+	constructed   map[string]bool  // target fields set by a constructor or builder
+	errReturn     string           // statement returning the zero target and err, empty if the method has no error result
+	body          strings.Builder  // generated statements
+}
+
+// collectFieldRules resolves the rule for every target field. Method comments
+// take precedence over target field tags, which take precedence over source
+// field tags; remaining fields are matched by name. Fields the generated code
+// cannot access are never matched by name. Rules naming a field that does
+// not exist or cannot be accessed are reported at the annotation declaring them.
+func (g *generator) collectFieldRules(config *MethodConfig, matcher *nameMatcher, targetStruct, sourceStruct *types.Struct) (map[string]FieldRule, error) {
+	rules := make(map[string]FieldRule)
+	ignoredSources := make(map[string]bool)
+
+	// source field tags name the target they feed
+	for i := range sourceStruct.NumFields() {
+		field := sourceStruct.Field(i)
+		rule, ok, err := parseFieldTag(field.Name(), sourceStruct.Tag(i), true)
+		if err != nil {
+			return nil, errorAt(g.positionOf(field), "source %v", err)
+		}
+		if !ok {
+			continue
+		}
+		rule.position = g.positionOf(field)
+		if rule.Ignore {
+			ignoredSources[field.Name()] = true
+			continue
+		}
+
+		targetName := rule.Source
+		if targetName == "" {
+			targetName = field.Name()
+			if findField(targetStruct, targetName) == nil {
+				continue
+			}
+		}
+		rule.Target = targetName
+		rule.Source = field.Name()
+		rules[targetName] = rule
+	}
+
+	// target field tags name the source they read from
+	for i := range targetStruct.NumFields() {
+		field := targetStruct.Field(i)
+		rule, ok, err := parseFieldTag(field.Name(), targetStruct.Tag(i), false)
+		if err != nil {
+			return nil, errorAt(g.positionOf(field), "target %v", err)
+		}
+		if !ok {
+			continue
+		}
+		rule.position = g.positionOf(field)
+
+		rule.Target = field.Name()
+		if rule.Source == "" && !rule.Ignore && findField(sourceStruct, field.Name()) != nil {
+			rule.Source = field.Name()
+		}
+		rules[field.Name()] = rule
+	}
+
+	// method comments override everything else
+	for _, rule := range config.Rules {
+		rules[rule.Target] = rule
+	}
+
+	// unexported fields of another package cannot be read by the generated code
+	unmatched := maps.Clone(ignoredSources)
+	for i := range sourceStruct.NumFields() {
+		if field := sourceStruct.Field(i); !g.accessible(field) {
+			unmatched[field.Name()] = true
+		}
+	}
+
+	// fall back to fields matched by name, also for rules that only set
+	// options such as nullValue or a default
+	for i := range targetStruct.NumFields() {
+		targetFieldName := targetStruct.Field(i).Name()
+		rule, ok := rules[targetFieldName]
+		if ok && (rule.Source != "" || rule.Ignore || rule.Constant != "" || rule.Expression != "") {
+			continue
+		}
+		if !ok && !g.accessible(targetStruct.Field(i)) {
+			continue
+		}
+
+		sourceFieldName, err := matcher.match(targetFieldName, targetStruct.Tag(i), sourceStruct, unmatched)
+		if err != nil {
+			return nil, err
+		}
+		if sourceFieldName == "" {
+			continue
+		}
+		if !ok {
+			rule = FieldRule{Target: targetFieldName}
+		}
+		rule.Source = sourceFieldName
+		rules[targetFieldName] = rule
+	}
+
+	// every rule must point at existing fields, the rules of the method
+	// comments are checked first and in the order they are written
+	checked := append(slices.Clone(config.Rules), slices.Collect(maps.Values(rules))...)
+	slices.SortStableFunc(checked[len(config.Rules):], func(a, b FieldRule) int {
+		return strings.Compare(a.Target, b.Target)
+	})
+	for _, rule := range checked {
+		targetField := findField(targetStruct, rule.Target)
+		if targetField == nil {
+			return nil, errorAt(rule.position, "target field %s%s does not exist", rule.Target, rule.describeOrigin())
+		}
+		if rule.Ignore {
+			continue
+		}
+		if !g.accessible(targetField) {
+			return nil, errorAt(rule.position, "target field %s%s is unexported in another package", rule.Target, rule.describeOrigin())
+		}
+		if rule.Source == "" {
+			continue
+		}
+		sourceField := findField(sourceStruct, rule.Source)
+		if sourceField == nil {
+			return nil, errorAt(rule.position, "source field %s%s does not exist", rule.Source, rule.describeOrigin())
+		}
+		if !g.accessible(sourceField) {
+			return nil, errorAt(rule.position, "source field %s%s is unexported in another package", rule.Source, rule.describeOrigin())
+		}
+	}
+
+	return rules, nil
+}
+
+// describeOrigin explains where a rule came from for error messages
+func (r FieldRule) describeOrigin() string {
+	if r.origin == "" {
+		return ""
+	}
+
+	return " (" + r.origin + ")"
+}
+
+// findField looks up a field of a struct by name
+func findField(structType *types.Struct, name string) *types.Var {
+	for i := range structType.NumFields() {
+		if structType.Field(i).Name() == name {
+			return structType.Field(i)
+		}
+	}
+
+	return nil
+}
+
+// writeFieldMappings writes one assignment per mapped target field, in declaration order
+func (m *methodWriter) writeFieldMappings(rules map[string]FieldRule, targetStruct, sourceStruct *types.Struct) error {
+	for i := range targetStruct.NumFields() {
+		targetField := targetStruct.Field(i)
+		rule, ok := rules[targetField.Name()]
+		if !ok || rule.Ignore || m.constructed[targetField.Name()] {
+			continue
+		}
+
+		var sourceField *types.Var
+		if rule.Source != "" {
+			sourceField = findField(sourceStruct, rule.Source)
+		}
+
+		if err := m.writeFieldAssignment(rule, "target."+targetField.Name(), targetField.Name(), targetField.Type(), sourceField); err != nil {
+			return fmt.Errorf("field %s: %v", targetField.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// writeFieldAssignment writes the statements populating one target field,
+// guarded by the condition of the rule when there is one
+func (m *methodWriter) writeFieldAssignment(rule FieldRule, targetExpr, name string, targetType types.Type, sourceField *types.Var) error {
+	assign, err := m.fieldAssignment(rule, targetExpr, name, targetType, sourceField)
+	if err != nil || assign == "" {
+		return err
+	}
+
+	condition, err := m.fieldCondition(rule, sourceField)
+	if err != nil {
+		return err
+	}
+	if condition == "" {
+		m.body.WriteString(assign)
+		return nil
+	}
+
+	m.body.WriteString(fmt.Sprintf("\tif %s {\n%s\t}\n", condition, assign))
+	return nil
+}
+
+// fieldAssignment renders the statements populating one target field, or
+// any other variable named by targetExpr
+func (m *methodWriter) fieldAssignment(rule FieldRule, targetExpr, name string, targetType types.Type, sourceField *types.Var) (string, error) {
+	switch {
+	case rule.Constant != "":
+		literal, err := basicLiteral(rule.Constant, targetType)
+		if err != nil {
+			return "", fmt.Errorf("constant: %v", err)
+		}
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, literal), nil
+
+	case rule.Expression != "":
+		m.usesSrc = true
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, rule.Expression), nil
+	}
+
+	var defaultValue string
+	if rule.Default != "" {
+		literal, err := basicLiteral(rule.Default, targetType)
+		if err != nil {
+			return "", err
+		}
+		defaultValue = literal
+	}
+
+	// constant default without a source
+	if sourceField == nil {
+		if defaultValue == "" {
+			return "", nil
+		}
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, defaultValue), nil
+	}
+
+	strategy := rule.NullValue
+	switch {
+	case strategy == "" && defaultValue != "":
+		strategy = "default"
+	case strategy == "":
+		strategy = m.nullValue
+	case strategy == "default" && defaultValue == "":
+		return "", fmt.Errorf("nullValue default requires a default value")
+	}
+
+	m.deepCopy = m.deepCopyAll
+	if rule.DeepCopy != nil {
+		m.deepCopy = *rule.DeepCopy
+	}
+
+	sourceExpr := m.paramName + "." + sourceField.Name()
+	m.fieldTarget = targetExpr
+	m.resetOnNil = m.update && strategy == "set"
+	m.omitNilGuard = strategy != "set"
+	assign, err := m.assignValue(targetExpr, sourceExpr, name, sourceField.Type(), targetType, rule.Format)
+	if err != nil {
+		return "", err
+	}
+
+	// set assigns zero values as they are, a nil source is handled by the guard of the assignment
+	if strategy == "set" {
+		return assign, nil
+	}
+
+	condition, err := m.g.nonZeroCondition(sourceExpr, sourceField.Type())
+	if err != nil {
+		return "", fmt.Errorf("nullValue %s on source field %s: %v", strategy, sourceField.Name(), err)
+	}
+	if strategy == "skip" {
+		return fmt.Sprintf("\tif %s {\n%s\t}\n", condition, assign), nil
+	}
+	if defaultValue == "" {
+		return "", fmt.Errorf("nullValue default requires a default value")
+	}
+	return fmt.Sprintf("\tif %s {\n%s\t} else {\n\t\t%s = %s\n\t}\n", condition, assign, targetExpr, defaultValue), nil
+}
+
+// applyOptions reads the options that change how fields are assigned
+func (m *methodWriter) applyOptions(options Options) error {
+	m.options = options
+	var err error
+	m.presenceCheck, err = options.flag("presenceCheck")
+	if err != nil {
+		return err
+	}
+	m.deepCopyAll, err = options.flag("deepCopy")
+	if err != nil {
+		return err
+	}
+	m.nullValue, err = nullValueStrategy(options["nullValue"])
+	if err != nil {
+		return err
+	}
+	m.g.scope = m.optionScope
+	m.subtypes, m.subFallback, err = m.g.subtypeMappings(options)
+	m.g.scope = nil
+	return err
+}
+
+// nullValueStrategy validates the nullValue option of a method, which defaults to set
+func nullValueStrategy(value string) (string, error) {
+	if value == "" {
+		return "set", nil
+	}
+	if !nullValueStrategies[value] {
+		return "", fmt.Errorf("invalid nullValue %q, expected set, skip or default", value)
+	}
+
+	return value, nil
+}
+
+// nilGuard wraps statements that may only run when sourceExpr is not nil.
+// On update methods with the set strategy the target field is cleared
+// otherwise; the guard is left out when the field is checked for a zero
+// value around the whole assignment.
+func (m *methodWriter) nilGuard(targetExpr, sourceExpr string, targetType types.Type, body string) string {
+	switch {
+	case targetExpr != m.fieldTarget || !m.omitNilGuard && !m.resetOnNil:
+		return fmt.Sprintf("\tif %s != nil {\n%s\t}\n", sourceExpr, body)
+	case m.omitNilGuard:
+		return body
+	}
+
+	return fmt.Sprintf("\tif %s != nil {\n%s\t} else {\n\t\t%s = %s\n\t}\n", sourceExpr, body, targetExpr, zeroValue(targetType, m.g.typeString))
+}
+
+// fieldCondition renders the guard of a field assignment. An explicit
+// condition is either a predicate method of the source such as HasEmail()
+// or an expression over src; with presenceCheck enabled a HasX() bool method
+// of the source guards field X automatically.
+func (m *methodWriter) fieldCondition(rule FieldRule, sourceField *types.Var) (string, error) {
+	if rule.Condition != "" {
+		if predicateCall.MatchString(rule.Condition) {
+			name := strings.TrimSuffix(rule.Condition, "()")
+			if !isPredicate(lookupHookMethod(m.sourceType, name)) {
+				return "", fmt.Errorf("condition %s is not a method of the source returning bool", rule.Condition)
+			}
+			return m.paramName + "." + rule.Condition, nil
+		}
+
+		m.usesSrc = true
+		return rule.Condition, nil
+	}
+
+	if m.presenceCheck && sourceField != nil {
+		name := "Has" + sourceField.Name()
+		if isPredicate(lookupHookMethod(m.sourceType, name)) {
+			return m.paramName + "." + name + "()", nil
+		}
+	}
+
+	return "", nil
+}
+
+// predicateCall matches a condition naming a method of the source, like HasEmail()
+var predicateCall = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\(\)$`)
+
+// isPredicate reports whether a method takes no parameters and returns a bool
+func isPredicate(method *types.Func) bool {
+	if method == nil {
+		return false
+	}
+
+	signature := method.Type().(*types.Signature)
+	if signature.Params().Len() != 0 || signature.Results().Len() != 1 {
+		return false
+	}
+	basic, ok := signature.Results().At(0).Type().Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Bool
+}
+
+// assignValue renders the statements assigning sourceExpr to targetExpr,
+// converting between the two types where possible. Pointers, slices, arrays
+// and maps whose elements need converting are handled element by element.
+func (m *methodWriter) assignValue(targetExpr, sourceExpr, name string, sourceType, targetType types.Type, layout string) (string, error) {
+	sourceType, targetType = types.Unalias(sourceType), types.Unalias(targetType)
+	if layout != "" {
+		return m.assignFormatted(targetExpr, sourceExpr, name, sourceType, targetType, layout)
+	}
+
+	if types.Identical(sourceType, targetType) {
+		if m.deepCopy {
+			return m.copyValue(targetExpr, sourceExpr, name, sourceType)
+		}
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, sourceExpr), nil
+	}
+	shared := m.deepCopy && m.needsDeepCopy(sourceType)
+
+	// used assemblers and config converters take precedence over built-in conversions
+	conv, err := m.g.findConverter(sourceType, targetType, m.contextName != "", m.origin.Assembler)
+	if err != nil {
+		return "", err
+	}
+	if conv != nil {
+		return m.assignConverted(targetExpr, sourceExpr, name, conv)
+	}
+
+	// a pair of types met again inside its own assignment is recursive and
+	// handled by a helper calling itself
+	if m.enterPair(sourceType, targetType) {
+		_, sourceNamed := sourceType.(*types.Named)
+		_, targetNamed := targetType.(*types.Named)
+		if !sourceNamed || !targetNamed {
+			return "", m.cycleError(sourceType, targetType)
+		}
+		return m.assignNested(targetExpr, sourceExpr, name, sourceType, targetType)
+	}
+	defer m.leavePair()
+
+	// interface values are dispatched on their concrete type when subtypes are listed
+	if types.IsInterface(sourceType) {
+		if assign, ok, err := m.assignSubtype(targetExpr, sourceExpr, name, sourceType, targetType); ok || err != nil {
+			return assign, err
+		}
+	}
+
+	if types.AssignableTo(sourceType, targetType) && !shared {
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, sourceExpr), nil
+	}
+
+	sourcePointer, sourceIsPointer := sourceType.(*types.Pointer)
+	targetPointer, targetIsPointer := targetType.(*types.Pointer)
+	switch {
+	// *S -> *T
+	case sourceIsPointer && targetIsPointer:
+		valueName := lowerFirst(name) + "Value"
+		assign, err := m.assignValue(valueName, "(*"+sourceExpr+")", name, sourcePointer.Elem(), targetPointer.Elem(), "")
+		if err != nil {
+			return "", err
+		}
+		body := fmt.Sprintf("\t\tvar %s %s\n%s\t\t%s = &%s\n", valueName, m.g.typeString(targetPointer.Elem()), assign, targetExpr, valueName)
+		return m.nilGuard(targetExpr, sourceExpr, targetType, body), nil
+
+	// *S -> T
+	case sourceIsPointer:
+		assign, err := m.assignValue(targetExpr, "(*"+sourceExpr+")", name, sourcePointer.Elem(), targetType, "")
+		if err != nil {
+			return "", err
+		}
+		return m.nilGuard(targetExpr, sourceExpr, targetType, assign), nil
+
+	// S -> *T, copy the value so the target does not alias the source
+	case targetIsPointer:
+		valueName := lowerFirst(name) + "Value"
+		if types.AssignableTo(sourceType, targetPointer.Elem()) && !shared {
+			return fmt.Sprintf("\t%s := %s\n\t%s = &%s\n", valueName, sourceExpr, targetExpr, valueName), nil
+		}
+		assign, err := m.assignValue(valueName, sourceExpr, name, sourceType, targetPointer.Elem(), "")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("\tvar %s %s\n%s\t%s = &%s\n", valueName, m.g.typeString(targetPointer.Elem()), assign, targetExpr, valueName), nil
+	}
+
+	if assign, ok, err := m.assignElements(targetExpr, sourceExpr, name, sourceType, targetType); ok || err != nil {
+		return assign, err
+	}
+
+	// integer -> string is a rune conversion in Go, never what a mapping wants
+	if isString(targetType) && isInteger(sourceType) {
+		return "", fmt.Errorf("cannot convert %s to %s without a format", sourceType, targetType)
+	}
+
+	// nested structs call the interface method declared for their types, so its
+	// rules apply even where a conversion would compile
+	if isStruct(sourceType) && isStruct(targetType) {
+		method, err := pickConverter(m.g.methods, nil, sourceType, targetType, m.contextName != "")
+		if err != nil {
+			return "", err
+		}
+		if method != nil {
+			return m.assignConverted(targetExpr, sourceExpr, name, method)
+		}
+	}
+
+	// converting a struct keeps the references inside it, a deep copy maps it
+	// field by field in a helper instead
+	if types.ConvertibleTo(sourceType, targetType) && !(shared && isStruct(sourceType) && isStruct(targetType)) {
+		return fmt.Sprintf("\t%s = %s(%s)\n", targetExpr, m.g.typeString(targetType), callArg(sourceExpr)), nil
+	}
+
+	// nested structs, named or anonymous, are mapped field by field in a helper
+	if isStruct(sourceType) && isStruct(targetType) {
+		return m.assignNested(targetExpr, sourceExpr, name, sourceType, targetType)
+	}
+
+	// values of type parameters are only known to the caller, another method
+	// of the interface converts them
+	if isTypeParam(sourceType) || isTypeParam(targetType) {
+		return m.assignTypeParam(targetExpr, sourceExpr, name, sourceType, targetType)
+	}
+
+	return "", fmt.Errorf("cannot assign %s to %s", sourceType, targetType)
+}
+
+// assignTypeParam renders a call to the interface method converting a value
+// of a type parameter, such as ToItem(item S) T, other than the method being
+// generated
+func (m *methodWriter) assignTypeParam(targetExpr, sourceExpr, name string, sourceType, targetType types.Type) (string, error) {
+	var methods []converter
+	itself := false
+	for _, method := range m.g.methods {
+		if method.name != m.origin.Name {
+			methods = append(methods, method)
+		} else if types.Identical(method.source, sourceType) && types.Identical(method.target, targetType) {
+			itself = true
+		}
+	}
+
+	method, err := pickConverter(methods, nil, sourceType, targetType, m.contextName != "")
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case method == nil && itself:
+		return "", fmt.Errorf("cannot generate a conversion from %s to %s, write %s by hand on the implementation",
+			sourceType, targetType, m.origin.Name)
+	case method == nil:
+		return "", fmt.Errorf("cannot assign %s to %s, declare a method converting %s to %s on the interface and write it by hand on the implementation",
+			sourceType, targetType, sourceType, targetType)
+	}
+
+	return m.assignConverted(targetExpr, sourceExpr, name, method)
+}
+
+// callArg drops the parentheses around a dereferenced pointer passed as an
+// argument, they are only needed when a selector follows it
+func callArg(expr string) string {
+	if strings.HasPrefix(expr, "(*") && strings.HasSuffix(expr, ")") && !strings.ContainsAny(expr[2:len(expr)-1], "()") {
+		return expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+// isTypeParam reports whether t is a type parameter
+func isTypeParam(t types.Type) bool {
+	_, ok := types.Unalias(t).(*types.TypeParam)
+	return ok
+}
+
+// assignConverted renders a call to a converter method
+func (m *methodWriter) assignConverted(targetExpr, sourceExpr, name string, conv *converter) (string, error) {
+	call := fmt.Sprintf("%s.%s(%s)", conv.receiver, conv.name, callArg(sourceExpr))
+	if conv.takesContext {
+		call = fmt.Sprintf("%s.%s(%s, %s)", conv.receiver, conv.name, m.contextName, callArg(sourceExpr))
+	}
+	if !conv.returnsError {
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, call), nil
+	}
+	if m.errReturn == "" {
+		return "", fmt.Errorf("%s returns an error but the method does not", conv.name)
+	}
+
+	convertedName := "converted" + name
+	return fmt.Sprintf("\t%s, err := %s\n\tif err != nil {\n\t\t%s\n\t}\n\t%s = %s\n",
+		convertedName, call, m.errReturn, targetExpr, convertedName), nil
+}
+
+// assignElements converts slices, arrays and maps element by element.
+// ok is false when the types are not a pair of containers of the same kind.
+func (m *methodWriter) assignElements(targetExpr, sourceExpr, name string, sourceType, targetType types.Type) (assign string, ok bool, err error) {
+	m.loopDepth++
+	defer func() { m.loopDepth-- }()
+	index := loopVar("i", m.loopDepth)
+
+	switch source := sourceType.Underlying().(type) {
+	case *types.Slice:
+		target, isSlice := targetType.Underlying().(*types.Slice)
+		if !isSlice {
+			return "", false, nil
+		}
+		element, err := m.assignValue(targetExpr+"["+index+"]", sourceExpr+"["+index+"]", name+"Item", source.Elem(), target.Elem(), "")
+		if err != nil {
+			return "", true, err
+		}
+		body := fmt.Sprintf("\t\t%s = make(%s, len(%s))\n\t\tfor %s := range %s {\n%s\t\t}\n",
+			targetExpr, m.g.typeString(targetType), sourceExpr, index, sourceExpr, element)
+		return m.nilGuard(targetExpr, sourceExpr, targetType, body), true, nil
+
+	case *types.Array:
+		target, isArray := targetType.Underlying().(*types.Array)
+		if !isArray || target.Len() != source.Len() {
+			return "", false, nil
+		}
+		element, err := m.assignValue(targetExpr+"["+index+"]", sourceExpr+"["+index+"]", name+"Item", source.Elem(), target.Elem(), "")
+		if err != nil {
+			return "", true, err
+		}
+		return fmt.Sprintf("\tfor %s := range %s {\n%s\t}\n", index, sourceExpr, element), true, nil
+
+	case *types.Map:
+		target, isMap := targetType.Underlying().(*types.Map)
+		if !isMap {
+			return "", false, nil
+		}
+		if !types.AssignableTo(source.Key(), target.Key()) {
+			return "", true, fmt.Errorf("cannot convert map key %s to %s", source.Key(), target.Key())
+		}
+		key, value := loopVar("key", m.loopDepth), loopVar("value", m.loopDepth)
+		element, err := m.assignValue(targetExpr+"["+key+"]", value, name+"Value", source.Elem(), target.Elem(), "")
+		if err != nil {
+			return "", true, err
+		}
+		body := fmt.Sprintf("\t\t%s = make(%s, len(%s))\n\t\tfor %s, %s := range %s {\n%s\t\t}\n",
+			targetExpr, m.g.typeString(targetType), sourceExpr, key, value, sourceExpr, element)
+		return m.nilGuard(targetExpr, sourceExpr, targetType, body), true, nil
+	}
+
+	return "", false, nil
+}
+
+// loopVar names a loop variable, adding the nesting depth below the outermost loop
+func loopVar(base string, depth int) string {
+	if depth <= 1 {
+		return base
+	}
+
+	return fmt.Sprintf("%s%d", base, depth)
+}
+
+// assignFormatted renders a conversion that uses the format of a rule
+func (m *methodWriter) assignFormatted(targetExpr, sourceExpr, name string, sourceType, targetType types.Type, layout string) (string, error) {
+	switch {
+	case isTime(sourceType) && isString(targetType):
+		formatted := fmt.Sprintf("%s.Format(%s)", sourceExpr, strconv.Quote(layout))
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, m.convertString(formatted, targetType)), nil
+
+	case isString(sourceType) && isTime(targetType):
+		if m.errReturn == "" {
+			return "", fmt.Errorf("parsing time requires the method to return an error")
+		}
+		timeName := m.g.addImport("time", "time")
+		parsedName := "parsed" + name
+		if !types.Identical(sourceType, types.Typ[types.String]) {
+			sourceExpr = "string(" + sourceExpr + ")"
+		}
+		return fmt.Sprintf("\t%s, err := %s.Parse(%s, %s)\n\tif err != nil {\n\t\t%s\n\t}\n\t%s = %s\n",
+			parsedName, timeName, strconv.Quote(layout), sourceExpr, m.errReturn, targetExpr, parsedName), nil
+
+	case isString(targetType):
+		fmtName := m.g.addImport("fmt", "fmt")
+		formatted := fmt.Sprintf("%s.Sprintf(%s, %s)", fmtName, strconv.Quote(layout), callArg(sourceExpr))
+		return fmt.Sprintf("\t%s = %s\n", targetExpr, m.convertString(formatted, targetType)), nil
+	}
+
+	return "", fmt.Errorf("format is not supported from %s to %s", sourceType, targetType)
+}
+
+// convertString wraps a string expression in a conversion when the target is a named string type
+func (m *methodWriter) convertString(expr string, targetType types.Type) string {
+	if types.Identical(targetType, types.Typ[types.String]) {
+		return expr
+	}
+
+	return m.g.typeString(targetType) + "(" + expr + ")"
+}
+
+// basicLiteral renders a default or constant value as a Go literal of the target type
+func basicLiteral(value string, targetType types.Type) (string, error) {
+	basic, ok := targetType.Underlying().(*types.Basic)
+	if !ok {
+		return "", fmt.Errorf("only basic types can be set from a literal, not %s", targetType)
+	}
+
+	switch {
+	case basic.Info()&types.IsString != 0:
+		return strconv.Quote(value), nil
+	case basic.Info()&types.IsBoolean != 0:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "", fmt.Errorf("invalid bool literal %q", value)
+		}
+	case basic.Info()&types.IsNumeric != 0:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("invalid numeric literal %q", value)
+		}
+	}
+
+	return value, nil
+}
+
+// nonZeroCondition renders an expression that is true when expr is not the
+// zero value. Structs that cannot be compared are tested field by field.
+func (g *generator) nonZeroCondition(expr string, t types.Type) (string, error) {
+	if isTime(t) {
+		return "!" + expr + ".IsZero()", nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return expr + ` != ""`, nil
+		case u.Info()&types.IsBoolean != 0:
+			return expr, nil
+		case u.Info()&types.IsNumeric != 0:
+			return expr + " != 0", nil
+		}
+	case *types.Pointer, *types.Map, *types.Interface, *types.Chan, *types.Signature:
+		return expr + " != nil", nil
+	case *types.Slice:
+		return "len(" + expr + ") != 0", nil
+	case *types.Struct:
+		if types.Comparable(t) {
+			return fmt.Sprintf("%s != (%s{})", expr, g.typeString(t)), nil
+		}
+
+		var conditions []string
+		for i := range u.NumFields() {
+			field := u.Field(i)
+			if field.Name() == "_" {
+				continue
+			}
+			if !g.accessible(field) {
+				return "", fmt.Errorf("cannot test %s for zero value, field %s is not accessible", t, field.Name())
+			}
+			condition, err := g.nonZeroCondition(expr+"."+field.Name(), field.Type())
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, condition)
+		}
+		return "(" + strings.Join(conditions, " || ") + ")", nil
+	case *types.Array:
+		if types.Comparable(t) {
+			return fmt.Sprintf("%s != (%s{})", expr, g.typeString(t)), nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot test %s for zero value", t)
+}
+
+// zeroValue renders the zero value of a type
+func zeroValue(t types.Type, typeString func(types.Type) string) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		}
+	case *types.Struct, *types.Array:
+		return typeString(t) + "{}"
+	}
+
+	return "nil"
+}
+
+// isTime reports whether t is time.Time
+func isTime(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+
+	return named.Obj().Pkg().Path() == "time" && named.Obj().Name() == "Time"
+}
+
+// isString reports whether the underlying type of t is string
+func isString(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsString != 0
+}
+
+// isInteger reports whether the underlying type of t is an integer
+func isInteger(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsInteger != 0
+}
+
+// upperFirst upper-cases the first letter of a name
+func upperFirst(name string) string {
+	if name == "" {
+		return name
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// lowerFirst lower-cases the first letter of a name
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
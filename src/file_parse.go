@@ -4,162 +4,298 @@ import (
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
+	"go/types"
+	"maps"
+	"path/filepath"
 	"strings"
 )
 
-// 表示解析到的接口信息
+// InterfaceInfo is an assembler interface found in a file
 type InterfaceInfo struct {
-	Name        string       // 接口名称
-	PackageName string       // 包名
-	Depends     []string     // 依赖的包
-	Methods     []MethodInfo // 接口方法
-	FilePath    string       // 文件路径
-	Comment     string       // 注释
+	Name        string       // interface name
+	PackageName string       // package name
+	Imports     []ImportInfo // imports of the interface file
+	Methods     []MethodInfo // interface methods
+	FilePath    string       // file path
+	Comment     string       // comment
+	Options     Options      // options declared on mapmap:assembler
+	Config      *ConfigInfo  // shared config the config option refers to
+	Uses        []TypeRef    // other assemblers the uses option refers to
+	TypeParams  []ParamInfo  // type parameters of a generic interface, Type holds the constraint
+
+	Position        token.Position // where the interface is declared
+	CommentPosition token.Position // where the mapmap:assembler comment is
 }
 
-// 表示接口方法信息
+// MethodInfo is a method of an assembler interface
 type MethodInfo struct {
-	Name    string      // 方法名称
-	Params  []ParamInfo // 参数信息
-	Results []ParamInfo // 返回值信息
-	Comment []string    // 方法注释
+	Name     string         // method name
+	Params   []ParamInfo    // parameters
+	Results  []ParamInfo    // results
+	Comment  []string       // method comment lines
+	Position token.Position // where the method is declared
+	Scope    *TypeScope     // where the method types are written for a method of an embedded interface, nil for the assembler's own methods
+
+	Assembler *EmbeddedAssembler // the embedded assembler the method belongs to, nil unless it comes from an annotated embedded interface
+
+	CommentPositions []token.Position // position of each comment line, matching Comment
 }
 
-// 表示参数或返回值信息
+// EmbeddedAssembler is an embedded interface with a mapmap:assembler comment.
+// Its options, shared config and uses apply to the methods it contributes,
+// the options of a method win.
+type EmbeddedAssembler struct {
+	Name    string             // interface name
+	Options Options            // options declared on mapmap:assembler
+	Config  *ConfigInfo        // shared config the config option refers to
+	Uses    []TypeRef          // other assemblers the uses option refers to
+	Scope   *TypeScope         // where the interface is declared, types in its options resolve there
+	Outer   *EmbeddedAssembler // the assembler embedding it, nil directly in the generated interface
+
+	Position token.Position // where the mapmap:assembler comment is
+}
+
+// TypeScope is where type expressions are written. The method types of an
+// embedded interface resolve in the package and file declaring it.
+type TypeScope struct {
+	PackagePath string       // import path of the declaring package
+	Imports     []ImportInfo // imports of the declaring file
+}
+
+// ImportInfo is an import of an interface file
+type ImportInfo struct {
+	Name string // name given to the import, empty when there is none
+	Path string // import path
+}
+
+// ParamInfo is a parameter or result
 type ParamInfo struct {
-	Name string // 参数名称
-	Type string // 参数类型
+	Name string // parameter name
+	Type string // parameter type
 }
 
-// ParseFile 解析单个Go文件，查找带有特定注释的接口定义
-func ParseFile(filePath string) ([]InterfaceInfo, error) {
-	// 创建文件集
+// ParseFile parses a Go file and finds the interfaces annotated with
+// mapmap:assembler. The loader shared by the run finds the packages that
+// comments and embedded interfaces refer to.
+func ParseFile(loader *Loader, filePath string) ([]InterfaceInfo, error) {
 	fset := token.NewFileSet()
 
-	// 解析Go文件
 	file, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
 	if err != nil {
-		return nil, fmt.Errorf("解析文件失败: %v", err)
+		// a syntax error points at the first position in error
+		if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+			return nil, errorAt(list[0].Pos, "%s", list[0].Msg)
+		}
+		return nil, fmt.Errorf("failed to parse file: %v", err)
 	}
 
-	// 获取包名
 	packageName := file.Name.Name
+	imports := fileImports(file)
 
-	// 获取依赖的包
-	depends := []string{}
-	for _, spec := range file.Imports {
-		depends = append(depends, strings.Trim(spec.Path.Value, "\""))
-	}
-
-	// 用于存储找到的接口信息
 	var interfaces []InterfaceInfo
-
-	// 遍历所有顶级声明
 	for _, decl := range file.Decls {
-		// 查找类型声明（如接口定义）
+		// look for type declarations, such as interfaces
 		genDecl, ok := decl.(*ast.GenDecl)
 		if !ok || genDecl.Tok != token.TYPE {
 			continue
 		}
 
-		// 检查注释
-		if genDecl.Doc == nil {
-			continue
-		}
-
-		// 查找特定注释
-		hasMapMapComment := false
-		for _, comment := range genDecl.Doc.List {
-			if strings.Contains(comment.Text, "mapmap:assembler") {
-				hasMapMapComment = true
-				break
-			}
-		}
-
-		if !hasMapMapComment {
-			continue
-		}
-
-		// 处理类型规范
 		for _, spec := range genDecl.Specs {
 			typeSpec, ok := spec.(*ast.TypeSpec)
 			if !ok {
 				continue
 			}
 
-			// 验证是否为接口类型
 			interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
 			if !ok {
 				continue
 			}
 
-			// 创建接口信息对象
+			// find the mapmap:assembler comment with its options, config and uses
+			assembler, err := loader.parseAssemblerDoc(fset, file, filePath, typeSpec.Name.Name, genDecl.Doc)
+			if err != nil {
+				return nil, err
+			}
+			if assembler == nil {
+				continue
+			}
+
 			ifaceInfo := InterfaceInfo{
 				Name:        typeSpec.Name.Name,
 				PackageName: packageName,
 				FilePath:    filePath,
 				Comment:     "mapmap:assembler",
-				Depends:     depends,
+				Imports:     imports,
+				Options:     assembler.Options,
+				Config:      assembler.Config,
+				Uses:        assembler.Uses,
+
+				Position:        fset.Position(typeSpec.Name.Pos()),
+				CommentPosition: assembler.Position,
 			}
 
-			// 解析接口方法
-			if interfaceType.Methods != nil {
-				for _, method := range interfaceType.Methods.List {
-					if len(method.Names) == 0 {
-						continue // 嵌入接口
+			// type parameters of a generic interface
+			if typeSpec.TypeParams != nil {
+				for _, field := range typeSpec.TypeParams.List {
+					for _, name := range field.Names {
+						ifaceInfo.TypeParams = append(ifaceInfo.TypeParams, ParamInfo{
+							Name: name.Name,
+							Type: types.ExprString(field.Type),
+						})
 					}
+				}
+			}
 
-					methodName := method.Names[0].Name
-					methodType, ok := method.Type.(*ast.FuncType)
-					if !ok {
-						continue
-					}
+			// parse the methods, expanding embedded interfaces into theirs
+			visiting := []string{embedKey(filepath.Dir(filePath), typeSpec.Name.Name)}
+			methods, err := loader.parseInterfaceMethods(fset, file, filePath, interfaceType, nil, nil, visiting)
+			if err != nil {
+				return nil, withContext(err, "failed to parse the methods of interface %s", typeSpec.Name.Name)
+			}
+			ifaceInfo.Methods = methods
 
-					methodInfo := MethodInfo{
-						Name: methodName,
-					}
+			interfaces = append(interfaces, ifaceInfo)
+		}
+	}
 
-					// 解析方法参数
-					if methodType.Params != nil {
-						methodInfo.Params = parseFieldList(methodType.Params)
-					}
+	return interfaces, nil
+}
 
-					// 解析方法返回值
-					if methodType.Results != nil {
-						methodInfo.Results = parseFieldList(methodType.Results)
-					}
+// parseAssemblerDoc parses the mapmap:assembler comment on the declaration of
+// an interface, with its options and the shared config and uses they refer
+// to. It returns nil when the interface has no such comment.
+func (l *Loader) parseAssemblerDoc(fset *token.FileSet, file *ast.File, filePath string, name string, doc *ast.CommentGroup) (*EmbeddedAssembler, error) {
+	if doc == nil {
+		return nil, nil
+	}
 
-					// 获取方法注释
-					if method.Doc != nil && len(method.Doc.List) > 0 {
-						for _, comment := range method.Doc.List {
-							methodInfo.Comment = append(methodInfo.Comment, comment.Text)
-						}
-					}
+	var assembler *EmbeddedAssembler
+	for _, comment := range doc.List {
+		if !strings.Contains(comment.Text, "mapmap:assembler") {
+			continue
+		}
+		if assembler == nil {
+			assembler = &EmbeddedAssembler{
+				Name:    name,
+				Options: make(Options),
+			}
+		}
+		assembler.Position = fset.Position(comment.Pos())
 
-					ifaceInfo.Methods = append(ifaceInfo.Methods, methodInfo)
-				}
+		// interface level options
+		commentOptions, err := parseAssemblerComment(comment.Text)
+		if err != nil {
+			return nil, errorAt(assembler.Position, "failed to parse the interface comment: %v", err)
+		}
+		maps.Copy(assembler.Options, commentOptions)
+	}
+	if assembler == nil {
+		return nil, nil
+	}
+
+	// the shared config referred to
+	if configRef := assembler.Options["config"]; configRef != "" {
+		config, err := l.resolveConfig(file, filePath, configRef)
+		if err != nil {
+			return nil, errorAt(assembler.Position, "failed to resolve the config of interface %s: %v", name, err)
+		}
+		assembler.Config = config
+	}
+
+	// the other assemblers used
+	for _, usesRef := range assembler.Options.list("uses") {
+		typeRef, _, err := l.resolveTypeRef(file, filePath, usesRef)
+		if err != nil {
+			return nil, errorAt(assembler.Position, "failed to resolve the uses of interface %s: %v", name, err)
+		}
+		assembler.Uses = append(assembler.Uses, typeRef)
+	}
+
+	return assembler, nil
+}
+
+// fileImports returns the imports of a file with their names
+func fileImports(file *ast.File) []ImportInfo {
+	imports := []ImportInfo{}
+	for _, spec := range file.Imports {
+		importInfo := ImportInfo{Path: strings.Trim(spec.Path.Value, "\"")}
+		if spec.Name != nil {
+			importInfo.Name = spec.Name.Name
+		}
+		imports = append(imports, importInfo)
+	}
+	return imports
+}
+
+// parseInterfaceMethods parses the methods of an interface declaration,
+// expanding embedded interfaces into theirs. scope is where the method types
+// are written and assembler the embedded assembler the methods belong to,
+// both nil for the assembler's own methods; visiting holds the interfaces
+// being expanded to find embedding cycles.
+func (l *Loader) parseInterfaceMethods(fset *token.FileSet, file *ast.File, filePath string, interfaceType *ast.InterfaceType, scope *TypeScope, assembler *EmbeddedAssembler, visiting []string) ([]MethodInfo, error) {
+	var methods []MethodInfo
+	if interfaceType.Methods == nil {
+		return methods, nil
+	}
+
+	for _, method := range interfaceType.Methods.List {
+		if len(method.Names) == 0 {
+			// an embedded interface
+			embedded, err := l.parseEmbeddedInterface(file, filePath, method.Type, assembler, visiting)
+			if err != nil {
+				return nil, atPosition(fset.Position(method.Pos()), withContext(err, "embedded %s", types.ExprString(method.Type)))
 			}
+			methods = append(methods, embedded...)
+			continue
+		}
 
-			interfaces = append(interfaces, ifaceInfo)
+		methodName := method.Names[0].Name
+		methodType, ok := method.Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+
+		methodInfo := MethodInfo{
+			Name:     methodName,
+			Position: fset.Position(method.Pos()),
+			Scope:    scope,
+
+			Assembler: assembler,
+		}
+
+		if methodType.Params != nil {
+			methodInfo.Params = parseFieldList(methodType.Params)
 		}
+
+		if methodType.Results != nil {
+			methodInfo.Results = parseFieldList(methodType.Results)
+		}
+
+		if method.Doc != nil && len(method.Doc.List) > 0 {
+			for _, comment := range method.Doc.List {
+				methodInfo.Comment = append(methodInfo.Comment, comment.Text)
+				methodInfo.CommentPositions = append(methodInfo.CommentPositions, fset.Position(comment.Pos()))
+			}
+		}
+
+		methods = append(methods, methodInfo)
 	}
 
-	return interfaces, nil
+	return methods, nil
 }
 
-// 解析字段列表（参数或返回值）
+// parseFieldList parses a list of parameters or results
 func parseFieldList(fieldList *ast.FieldList) []ParamInfo {
 	var params []ParamInfo
 
 	for _, field := range fieldList.List {
-		// 获取类型表示和包路径
 		typeExpr := field.Type
 		typeStr := exprToString(typeExpr)
 
-		// 处理可能的多个名称
 		if len(field.Names) == 0 {
-			// 匿名参数
+			// an unnamed parameter
 			params = append(params, ParamInfo{
 				Name: "",
 				Type: typeStr,
@@ -177,7 +313,7 @@ func parseFieldList(fieldList *ast.FieldList) []ParamInfo {
 	return params
 }
 
-// 将类型表达式转换为字符串
+// exprToString renders a type expression as written
 func exprToString(expr ast.Expr) string {
 	switch t := expr.(type) {
 	case *ast.Ident:
@@ -190,108 +326,20 @@ func exprToString(expr ast.Expr) string {
 		if t.Len == nil {
 			return "[]" + exprToString(t.Elt)
 		}
-		// 固定长度数组暂不处理
-		return "array"
+		return "[" + types.ExprString(t.Len) + "]" + exprToString(t.Elt)
 	case *ast.MapType:
 		return "map[" + exprToString(t.Key) + "]" + exprToString(t.Value)
-	default:
-		return "未知类型"
-	}
-}
-
-// 创建一个用于生成代码的结构体信息
-type StructInfo struct {
-	Name   string
-	Fields []FieldInfo
-}
-
-// 字段信息
-type FieldInfo struct {
-	Name      string
-	Type      string
-	MapSource string // mapsource 标签值
-}
-
-// 查找并解析结构体信息
-func FindStructInfo(filePath string, structName string) (*StructInfo, error) {
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, decl := range file.Decls {
-		genDecl, ok := decl.(*ast.GenDecl)
-		if !ok || genDecl.Tok != token.TYPE {
-			continue
+	case *ast.IndexExpr:
+		// an instantiated generic type, such as Page[S]
+		return exprToString(t.X) + "[" + exprToString(t.Index) + "]"
+	case *ast.IndexListExpr:
+		indices := make([]string, 0, len(t.Indices))
+		for _, index := range t.Indices {
+			indices = append(indices, exprToString(index))
 		}
-
-		for _, spec := range genDecl.Specs {
-			typeSpec, ok := spec.(*ast.TypeSpec)
-			if !ok || typeSpec.Name.Name != structName {
-				continue
-			}
-
-			structType, ok := typeSpec.Type.(*ast.StructType)
-			if !ok {
-				continue
-			}
-
-			structInfo := &StructInfo{
-				Name: structName,
-			}
-
-			// 解析结构体字段
-			if structType.Fields != nil {
-				for _, field := range structType.Fields.List {
-					if len(field.Names) == 0 {
-						continue // 匿名字段
-					}
-
-					fieldName := field.Names[0].Name
-					fieldType := exprToString(field.Type)
-
-					fieldInfo := FieldInfo{
-						Name: fieldName,
-						Type: fieldType,
-					}
-
-					// 解析 mapsource 标签
-					if field.Tag != nil {
-						tag := field.Tag.Value
-						mapSourceTag := extractTag(tag, "mapsource")
-						fieldInfo.MapSource = mapSourceTag
-					}
-
-					structInfo.Fields = append(structInfo.Fields, fieldInfo)
-				}
-			}
-
-			return structInfo, nil
-		}
-	}
-
-	return nil, fmt.Errorf("未找到结构体: %s", structName)
-}
-
-// 从标签字符串中提取特定标签
-func extractTag(tagStr string, tagName string) string {
-	// 移除首尾的反引号
-	tagStr = strings.Trim(tagStr, "`")
-
-	// 寻找目标标签
-	tagPrefix := tagName + ":\""
-	start := strings.Index(tagStr, tagPrefix)
-	if start == -1 {
-		return ""
-	}
-
-	// 移动到标签值的开始
-	start += len(tagPrefix)
-	end := strings.Index(tagStr[start:], "\"")
-	if end == -1 {
-		return ""
+		return exprToString(t.X) + "[" + strings.Join(indices, ", ") + "]"
+	default:
+		// anonymous structs and other types render as in the source
+		return types.ExprString(expr)
 	}
-
-	return tagStr[start : start+end]
 }
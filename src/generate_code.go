@@ -1,37 +1,253 @@
 package src
 
 import (
+	"errors"
 	"fmt"
+	"go/format"
 	"go/types"
-	"maps"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-// GenerateCode generates implementation code for an interface
-func GenerateCode(iface InterfaceInfo, outputDir string) error {
+// generator holds the state shared by all methods of one interface
+type generator struct {
+	iface       InterfaceInfo
+	importer    *moduleImporter             // loads every package of the interface so types compare identical
+	options     Options                     // shared config options overridden by the interface options
+	configs     []*types.TypeName           // shared config types embedded in the implementation, the interface's first
+	uses        []usedAssembler             // assemblers the implementation delegates to
+	hooks       []hookInfo                  // hand-written before and after hooks on the implementation
+	converters  []converter                 // methods of used assemblers and the shared config
+	methods     []converter                 // interface methods creating a new target, called for nested values of their types
+	typeParams  map[string]*types.TypeParam // type parameters of a generic interface
+	typeContext *types.Context              // shares identical instances of generic types
+	imports     []importSpec                // imports used by the generated code in emission order
+	importNames map[string]string           // import path to the name it is referred to by
+	helpers     []*nestedHelper             // helpers for nested and recursive types, in order of first use
+	scope       *TypeScope                  // where the types being resolved are written, nil for the interface file
+}
+
+// importSpec is an import of the generated file
+type importSpec struct {
+	name  string // name the generated code refers to the package by
+	path  string // import path
+	alias bool   // the name differs from the package name and must be written
+}
+
+// newGenerator creates a generator seeded with the interface imports
+func newGenerator(iface InterfaceInfo, imp *moduleImporter) *generator {
+	g := &generator{
+		iface:       iface,
+		importer:    imp,
+		options:     iface.Options,
+		typeContext: types.NewContext(),
+		importNames: make(map[string]string),
+	}
+
+	return g
+}
+
+// addImport records an import needed by the generated code and returns the
+// name to qualify its identifiers with. The name the interface file imports
+// the package under is kept; clashing names get a numeric suffix.
+func (g *generator) addImport(importPath, packageName string) string {
+	if name, ok := g.importNames[importPath]; ok {
+		return name
+	}
+
+	name := packageName
+	for _, imported := range g.iface.Imports {
+		if imported.Path == importPath && imported.Name != "" && imported.Name != "_" && imported.Name != "." {
+			name = imported.Name
+		}
+	}
+	for i := 2; g.importNameTaken(name); i++ {
+		name = fmt.Sprintf("%s%d", packageName, i)
+	}
+
+	g.importNames[importPath] = name
+	g.imports = append(g.imports, importSpec{name: name, path: importPath, alias: name != packageName})
+	return name
+}
+
+// importNameTaken reports whether an import already uses a name, or a
+// parameter of a generated method would shadow it. Methods of interfaces
+// embedded from other packages may name parameters after their packages.
+func (g *generator) importNameTaken(name string) bool {
+	for _, spec := range g.imports {
+		if spec.name == name {
+			return true
+		}
+	}
+	for _, method := range g.iface.Methods {
+		for _, param := range method.Params {
+			if param.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// qualifier renders package qualifiers for go/types type strings
+func (g *generator) qualifier(pkg *types.Package) string {
+	if g.isLocal(pkg) {
+		return ""
+	}
+	return g.addImport(pkg.Path(), pkg.Name())
+}
+
+// accessible reports whether the generated code may set or read a field
+func (g *generator) accessible(field *types.Var) bool {
+	return field.Exported() || field.Pkg() != nil && g.isLocal(field.Pkg())
+}
+
+// isLocal reports whether a package is the one the implementation is generated into
+func (g *generator) isLocal(pkg *types.Package) bool {
+	return pkg.Path() == g.importer.pkgPath
+}
+
+// findConverter returns the converter for a pair of types, or nil. The
+// converters of the embedded assembler a method comes from are searched
+// before those of the assemblers embedding it, the interface's last.
+func (g *generator) findConverter(sourceType, targetType types.Type, hasContext bool, assembler *EmbeddedAssembler) (*converter, error) {
+	for owner := assembler; ; owner = owner.Outer {
+		found, err := pickConverter(g.converters, owner, sourceType, targetType, hasContext)
+		if found != nil || err != nil || owner == nil {
+			return found, err
+		}
+	}
+}
+
+// pickConverter returns the converter of one assembler for a pair of types,
+// or nil. Used assemblers are searched before the shared config; two
+// candidates from the same type are ambiguous unless only one takes the
+// context of the method. Converters taking a context are only used when the
+// method has one.
+func pickConverter(converters []converter, owner *EmbeddedAssembler, sourceType, targetType types.Type, hasContext bool) (*converter, error) {
+	var found *converter
+	for i := range converters {
+		conv := &converters[i]
+		if conv.owner != owner {
+			continue
+		}
+		if !types.Identical(conv.source, sourceType) || !types.Identical(conv.target, targetType) {
+			continue
+		}
+		if conv.takesContext && !hasContext {
+			continue
+		}
+		if found == nil {
+			found = conv
+			continue
+		}
+		if found.receiver != conv.receiver {
+			continue
+		}
+		if found.takesContext == conv.takesContext {
+			return nil, fmt.Errorf("both %s.%s and %s.%s convert %s to %s",
+				found.receiver, found.name, conv.receiver, conv.name, sourceType, targetType)
+		}
+		if conv.takesContext {
+			found = conv
+		}
+	}
+
+	return found, nil
+}
+
+// warn prints a diagnostic that does not stop generation
+func (g *generator) warn(err error) {
+	fmt.Printf("warning: %v\n", err)
+}
+
+// typeString renders a type as it should appear in the generated file
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+// GenerateCode generates implementation code for an interface, loading
+// packages through the loader shared by the whole run
+func GenerateCode(loader *Loader, iface InterfaceInfo, outputDir string) error {
 	// Check if interface has methods
 	if len(iface.Methods) == 0 {
-		return fmt.Errorf("interface %s has no methods", iface.Name)
+		return errorAt(iface.Position, "interface %s has no methods", iface.Name)
 	}
 
-	// Generate implementation structure
-	implStruct, err := generateImplStruct(iface)
+	// Load packages the way the go command resolves them from the interface's module
+	imp, err := loader.importerFor(filepath.Dir(iface.FilePath))
 	if err != nil {
-		return fmt.Errorf("failed to generate implementation structure: %v", err)
+		return fmt.Errorf("failed to locate module: %v", err)
+	}
+
+	g := newGenerator(iface, imp)
+	if err := g.loadTypeParams(); err != nil {
+		return atPosition(iface.Position, err)
+	}
+
+	// Embedded interfaces may bring in a method more than once
+	if err := g.dedupMethods(); err != nil {
+		return atPosition(iface.Position, err)
+	}
+
+	// Nested values call the interface methods declared for their types
+	g.loadMethodConverters()
+
+	// Find the hooks written by hand on the implementation
+	hooks, written, err := loader.findImplMethods(outputDir, iface.Name+"Impl")
+	if err != nil {
+		return atPosition(iface.Position, withContext(err, "failed to find hooks"))
+	}
+	g.hooks = hooks
+
+	// Load the assemblers this one delegates to
+	if err := g.loadUses(iface.Uses, nil); err != nil {
+		return atPosition(iface.CommentPosition, err)
+	}
+
+	// Load the shared config, its options sit below the interface options
+	if iface.Config != nil {
+		if err := g.loadConfig(iface.Config, nil); err != nil {
+			return atPosition(iface.CommentPosition, err)
+		}
+		g.options = iface.Config.Options.merge(iface.Options)
+	}
+
+	// Embedded assemblers bring their own config and used assemblers
+	if err := g.loadEmbeddedAssemblers(); err != nil {
+		return err
 	}
 
 	// Generate implementations for each method
-	for _, method := range iface.Methods {
-		implStruct, err = generateMethodImplementation(iface, method, implStruct)
+	methods := strings.Builder{}
+	for _, method := range g.iface.Methods {
+		// methods written by hand on the implementation are called, not generated
+		if written[method.Name] {
+			continue
+		}
+		methodImpl, err := g.generateMethodImplementation(method)
 		if err != nil {
-			return fmt.Errorf("failed to generate implementation for method %s: %v", method.Name, err)
+			return atPosition(method.Position, withContext(err, "failed to generate implementation for method %s", method.Name))
 		}
+		methods.WriteString(methodImpl)
+	}
+
+	// Helpers for nested and recursive types follow the methods needing them
+	helpers, err := g.generateHelpers()
+	if err != nil {
+		return withContext(err, "failed to generate nested mapping")
+	}
+	methods.WriteString(helpers)
+
+	// Generate implementation structure, imports are known once methods are done
+	implStruct, err := g.generateImplStruct()
+	if err != nil {
+		return fmt.Errorf("failed to generate implementation structure: %v", err)
 	}
 
 	// Write implementation to file
-	if err := writeImplStructToFile(implStruct, outputDir); err != nil {
+	if err := writeImplStructToFile(implStruct+methods.String(), outputDir); err != nil {
 		return fmt.Errorf("failed to write implementation to file: %v", err)
 	}
 
@@ -39,147 +255,354 @@ func GenerateCode(iface InterfaceInfo, outputDir string) error {
 }
 
 // generateImplStruct creates the basic structure for the implementation class
-func generateImplStruct(iface InterfaceInfo) (string, error) {
+func (g *generator) generateImplStruct() (string, error) {
 	// Create implementation name (interface name + Impl)
-	implName := iface.Name + "Impl"
+	implName := g.iface.Name + "Impl"
+
+	// Add type definition, embedding the shared config so its converters can be
+	// called. It is rendered first as the configs and uses may need imports.
+	body := strings.Builder{}
+	body.WriteString(fmt.Sprintf("// Auto-generated implementation of %s interface\ntype %s%s struct {\n", g.iface.Name, implName, g.typeParamList(true)))
+	for _, config := range g.configs {
+		body.WriteString(fmt.Sprintf("\t%s\n", g.typeString(config.Type())))
+	}
+	for _, used := range g.uses {
+		body.WriteString(fmt.Sprintf("\t%s %s\n", used.field, g.typeString(used.typeName.Type())))
+	}
+	body.WriteString("}\n\n")
+
+	body.WriteString(g.generateConstructor(implName))
 
 	// Create basic structure with package declaration
 	sb := strings.Builder{}
-	sb.WriteString(fmt.Sprintf("package %s\n\n", iface.PackageName))
+	sb.WriteString(fmt.Sprintf("package %s\n\n", g.iface.PackageName))
 
 	// Add import statements
-	if len(iface.Depends) > 0 {
+	if len(g.imports) > 0 {
 		sb.WriteString("import (\n")
-		for _, importPath := range iface.Depends {
-			sb.WriteString(fmt.Sprintf("\t\"%s\"\n", importPath))
+		for _, spec := range g.imports {
+			if spec.alias {
+				sb.WriteString(fmt.Sprintf("\t%s %q\n", spec.name, spec.path))
+			} else {
+				sb.WriteString(fmt.Sprintf("\t%q\n", spec.path))
+			}
 		}
 		sb.WriteString(")\n\n")
 	}
-
-	// Add type definition
-	sb.WriteString(fmt.Sprintf("// Auto-generated implementation of %s interface\ntype %s struct {\n}\n\n",
-		iface.Name, implName))
+	sb.WriteString(body.String())
 
 	return sb.String(), nil
 }
 
-// generateMethodImplementation creates the implementation for a single method
-func generateMethodImplementation(iface InterfaceInfo, method MethodInfo, implStruct string) (string, error) {
-	if len(method.Params) == 0 || len(method.Results) == 0 {
-		return "", fmt.Errorf("method %s must have at least one parameter and one return value", method.Name)
+// methodShape describes how a method receives its source and target
+type methodShape struct {
+	contextName  string // name of the leading context.Context parameter, empty if there is none
+	sourceName   string // name of the source parameter
+	sourceType   string // type of the source parameter
+	targetName   string // name of the target parameter of an update method
+	targetType   string // type of the result, or of the target parameter of an update method
+	update       bool   // the method fills an existing target passed as the second parameter
+	returnsError bool   // the last result is an error
+}
+
+// shapeOf classifies a method as creating a new target, func(S) T or
+// func(S) (T, error), or updating an existing one, func(S, *T) or
+// func(S, *T) error. Any of them may take a context.Context first.
+func (g *generator) shapeOf(method MethodInfo) (methodShape, error) {
+	resultsError := len(method.Results) > 0 && method.Results[len(method.Results)-1].Type == "error"
+
+	params := method.Params
+	var contextName string
+	if len(params) > 0 && g.isContextType(params[0].Type, method.Scope) {
+		contextName = params[0].Name
+		if contextName == "" || contextName == "_" {
+			contextName = "ctx"
+		}
+		params = params[1:]
 	}
 
-	// Get parameter and return types
-	sourceType := method.Params[0].Type
-	targetType := method.Results[0].Type
+	var shape methodShape
+	switch {
+	case len(params) == 1 && (len(method.Results) == 1 || len(method.Results) == 2 && resultsError):
+		shape = methodShape{
+			sourceName: params[0].Name,
+			sourceType: params[0].Type,
+			targetType: method.Results[0].Type,
+		}
+	case len(params) == 2 && strings.HasPrefix(params[1].Type, "*") && (len(method.Results) == 0 || len(method.Results) == 1 && resultsError):
+		shape = methodShape{
+			sourceName: params[0].Name,
+			sourceType: params[0].Type,
+			targetName: params[1].Name,
+			targetType: params[1].Type,
+			update:     true,
+		}
+	default:
+		return methodShape{}, fmt.Errorf("method %s must look like func(S) T, func(S) (T, error), func(S, *T) or func(S, *T) error, optionally taking a context.Context first", method.Name)
+	}
+	shape.contextName = contextName
+	shape.returnsError = resultsError
 
-	// Create method implementation template
-	sb := strings.Builder{}
-	sb.WriteString(fmt.Sprintf(`
-// %s implements conversion logic
-func (a *%sImpl) %s(%s %s) %s {
-	target := %s{}
+	if shape.sourceName == "" || shape.sourceName == "_" {
+		shape.sourceName = "src"
+	}
+	if shape.update && (shape.targetName == "" || shape.targetName == "_") {
+		shape.targetName = "target"
+	}
+
+	return shape, nil
+}
 
-`, method.Name, iface.Name, method.Name, method.Params[0].Name, sourceType, targetType, targetType))
+// isContextType reports whether a type written where scope points to is
+// context.Context, whatever name the file imports the context package under.
+// A type that does not resolve is not a context.
+func (g *generator) isContextType(expr string, scope *TypeScope) bool {
+	saved := g.scope
+	g.scope = scope
+	defer func() { g.scope = saved }()
 
-	// Process type information for mapping
-	dependenceMap, err := buildDependencyMap(iface, sourceType, targetType)
+	t, err := g.resolveType(expr)
+	return err == nil && isContext(t)
+}
+
+// loadMethodConverters resolves the interface methods creating a new target,
+// so nested values of their types are mapped under the rules declared on them.
+// Methods whose types do not resolve are reported when they are generated.
+func (g *generator) loadMethodConverters() {
+	for _, method := range g.iface.Methods {
+		shape, err := g.shapeOf(method)
+		if err != nil || shape.update {
+			continue
+		}
+
+		g.scope = method.Scope
+		sourceType, sourceErr := g.resolveType(shape.sourceType)
+		targetType, targetErr := g.resolveType(shape.targetType)
+		g.scope = nil
+		if sourceErr != nil || targetErr != nil {
+			continue
+		}
+
+		g.methods = append(g.methods, converter{
+			receiver:     "a",
+			name:         method.Name,
+			source:       sourceType,
+			target:       targetType,
+			returnsError: shape.returnsError,
+			takesContext: shape.contextName != "",
+		})
+	}
+}
+
+// generateMethodImplementation creates the implementation for a single method
+func (g *generator) generateMethodImplementation(method MethodInfo) (string, error) {
+	shape, err := g.shapeOf(method)
 	if err != nil {
 		return "", err
 	}
 
-	// Get struct information
-	targetStruct, err := getStruct(targetType, dependenceMap)
+	paramName := shape.sourceName
+	returnsError := shape.returnsError
+
+	// Resolve the types where the method is declared, instantiating generic ones
+	g.scope = method.Scope
+	defer func() { g.scope = nil }()
+	sourceParamType, err := g.resolveType(shape.sourceType)
 	if err != nil {
-		return "", fmt.Errorf("failed to get target struct info: %v", err)
+		return "", fmt.Errorf("failed to resolve source type: %v", err)
 	}
-
-	sourceStruct, err := getStruct(sourceType, dependenceMap)
+	targetResultType, err := g.resolveType(shape.targetType)
 	if err != nil {
-		return "", fmt.Errorf("failed to get source struct info: %v", err)
+		return "", fmt.Errorf("failed to resolve target type: %v", err)
+	}
+	g.scope = nil
+
+	// Render the types as the generated file imports them
+	sourceType := g.typeString(sourceParamType)
+	targetType := g.typeString(targetResultType)
+
+	// Get struct information, a source or target that is not a struct is assigned as a whole
+	targetName, targetStruct, targetErr := getStruct(targetResultType)
+	_, sourceStruct, sourceErr := getStruct(sourceParamType)
+	wholeValue := targetErr != nil || sourceErr != nil
+
+	// Build the statements that return early and at the end of the method
+	zeroTarget := zeroValue(targetResultType, g.typeString)
+	constructTarget := "target := " + zeroTarget
+	if pointer, ok := types.Unalias(targetResultType).(*types.Pointer); ok {
+		constructTarget = "target := &" + zeroValue(pointer.Elem(), g.typeString)
+	}
+	if wholeValue {
+		constructTarget = "var target " + targetType
 	}
 
-	// Add field mapping logic for matching field names
-	generateFieldMappings(&sb, method.Params[0].Name, method.Comment, targetStruct, sourceStruct)
-
-	sb.WriteString(`
-	return target
-}
-`)
+	var earlyReturn, finalReturn, errReturn string
+	switch {
+	case shape.update && returnsError:
+		earlyReturn, finalReturn, errReturn = "return nil", "return nil", "return err"
+	case shape.update:
+		earlyReturn, finalReturn = "return", ""
+	case returnsError:
+		earlyReturn, finalReturn, errReturn = "return "+zeroTarget+", nil", "return target, nil", "return "+zeroTarget+", err"
+	default:
+		earlyReturn, finalReturn = "return "+zeroTarget, "return target"
+	}
+	if shape.update {
+		constructTarget = ""
+		if shape.targetName != "target" {
+			constructTarget = "target := " + shape.targetName
+		}
+	}
 
-	// Add method implementation to the structure
-	return implStruct + sb.String(), nil
-}
+	m := &methodWriter{
+		g:           g,
+		origin:      method,
+		paramName:   paramName,
+		contextName: shape.contextName,
+		sourceType:  sourceParamType,
+		errReturn:   errReturn,
+		update:      shape.update,
+	}
 
-// getStruct 获取结构体信息
-func getStruct(targetType string, dependenceMap map[string]string) (*types.Struct, error) {
-	targetTypeParts := strings.Split(targetType, ".")
-	targetStruct, err := getStructInfo(dependenceMap[targetTypeParts[1]], targetTypeParts[1])
+	// Method options override those of the embedded assembler the method
+	// comes from, which override the interface options
+	config, err := g.resolveMethodConfig(method)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	options := g.assemblerOptions(method.Assembler).merge(config.Options)
 
-	return targetStruct, nil
-}
+	// Types named by the subtype option are written where the option is
+	m.optionScope = subtypeScope(method, config)
+	if err := m.applyOptions(options); err != nil {
+		return "", err
+	}
 
-// buildDependencyMap creates a map of type names to their package paths
-func buildDependencyMap(iface InterfaceInfo, sourceType, targetType string) (map[string]string, error) {
-	targetTypeParts := strings.Split(targetType, ".")
-	sourceTypeParts := strings.Split(sourceType, ".")
-	dependenceMap := make(map[string]string)
+	if wholeValue {
+		if err := m.writeWholeValue(config, targetResultType, errors.Join(sourceErr, targetErr)); err != nil {
+			return "", err
+		}
+	} else {
+		matcher, err := newNameMatcher(options)
+		if err != nil {
+			return "", err
+		}
 
-	// Find the corresponding packages for source and target types
-	for _, dependencePath := range iface.Depends {
-		if len(dependenceMap) == 2 {
-			break
-		} else if strings.LastIndex(dependencePath, targetTypeParts[0]) != -1 {
-			dependenceMap[targetTypeParts[1]] = dependencePath
-			continue
-		} else if strings.LastIndex(dependencePath, sourceTypeParts[0]) != -1 {
-			dependenceMap[sourceTypeParts[1]] = dependencePath
-			continue
+		// Add field mapping logic for matching field names
+		rules, err := g.collectFieldRules(config, matcher, targetStruct, sourceStruct)
+		if err != nil {
+			return "", err
+		}
+		var targetTypeName *types.TypeName
+		if targetName != nil {
+			targetTypeName = targetName.Obj()
+		}
+		constructed, err := m.writeConstruction(options, matcher, rules, targetTypeName, targetResultType, targetStruct, sourceStruct)
+		if err != nil {
+			return "", err
+		}
+		if constructed {
+			constructTarget = ""
+		}
+		if err := g.checkUnmappedTargets(method, options, targetType, rules, targetStruct); err != nil {
+			return "", err
+		}
+		if err := g.checkUnmappedSources(method, options, sourceType, rules, sourceStruct); err != nil {
+			return "", err
+		}
+		if err := m.writeFieldMappings(rules, targetStruct, sourceStruct); err != nil {
+			return "", err
 		}
 	}
 
-	return dependenceMap, nil
-}
+	// Lifecycle hooks run around the field mappings
+	beforeHooks, afterHooks, err := m.hookStatements(sourceType, targetType, sourceParamType, targetResultType)
+	if err != nil {
+		return "", err
+	}
 
-// generateFieldMappings generates code to map fields with matching names
-func generateFieldMappings(sb *strings.Builder, paramName string, methodComment []string, targetStruct, sourceStruct *types.Struct) {
-	// get targetFildName and sourceFieldName
-	targetSourceMap := make(map[string]string)
+	// Create method implementation template
+	params := paramName + " " + sourceType
+	if shape.contextName != "" {
+		params = shape.contextName + " " + g.addImport("context", "context") + ".Context, " + params
+	}
+	if shape.update {
+		params += ", " + shape.targetName + " " + targetType
+	}
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf(`
+// %s implements conversion logic
+func (a *%sImpl%s) %s(%s) %s {
+`, method.Name, g.iface.Name, g.typeParamList(false), method.Name, params, resultSignature(shape, targetType)))
 
-	for _, comment := range methodComment {
-		targetSourceMap2, err := parseMethodComment(comment)
-		if err != nil {
-			fmt.Println(err.Error())
-			continue
-		}
+	if strings.HasPrefix(sourceType, "*") {
+		sb.WriteString(fmt.Sprintf("\tif %s == nil {\n\t\t%s\n\t}\n\n", paramName, earlyReturn))
+	}
+	if shape.update {
+		sb.WriteString(fmt.Sprintf("\tif %s == nil {\n\t\t%s\n\t}\n\n", shape.targetName, earlyReturn))
+	}
 
-		maps.Copy(targetSourceMap, targetSourceMap2)
+	if beforeHooks != "" {
+		sb.WriteString(beforeHooks + "\n")
 	}
 
-	for i := range targetStruct.NumFields() {
-		if _, ok := targetSourceMap[targetStruct.Field(i).Name()]; ok {
-			continue
-		}
+	if constructTarget != "" {
+		sb.WriteString("\t" + constructTarget + "\n")
+	}
+	if m.usesSrc && paramName != "src" {
+		sb.WriteString("\tsrc := " + paramName + "\n")
+	}
+	if constructTarget != "" || m.usesSrc && paramName != "src" {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(m.body.String())
 
-		targetFieldName := targetStruct.Field(i).Name()
+	if afterHooks != "" {
+		sb.WriteString("\n" + afterHooks)
+	}
 
-		for j := range sourceStruct.NumFields() {
-			sourceFieldName := sourceStruct.Field(j).Name()
-			if targetFieldName == sourceFieldName {
-				targetSourceMap[targetFieldName] = sourceFieldName
-				break
-			}
-		}
+	if finalReturn != "" {
+		sb.WriteString("\n\t" + finalReturn + "\n")
+	}
+	sb.WriteString("}\n")
+
+	return sb.String(), nil
+}
+
+// writeWholeValue assigns the source to the target as one value, for methods
+// whose source or target is not a struct, such as func(UserID) string or
+// func([]User) []UserDTO. Field rules cannot apply to them.
+func (m *methodWriter) writeWholeValue(config *MethodConfig, targetType types.Type, notStruct error) error {
+	if len(config.Rules) > 0 {
+		return fmt.Errorf("field rules need a struct source and target: %v", notStruct)
+	}
+
+	targetExpr := "target"
+	if m.update {
+		targetExpr = "*target"
+		targetType = types.Unalias(targetType).(*types.Pointer).Elem()
 	}
 
-	// map the fields
-	for targetField, sourceField := range targetSourceMap {
-		sb.WriteString(fmt.Sprintf("\ttarget.%s = %s.%s\n",
-			targetField, paramName, sourceField))
+	m.deepCopy = m.deepCopyAll
+	assign, err := m.assignValue(targetExpr, m.paramName, "Result", m.sourceType, targetType, "")
+	if err != nil {
+		return err
+	}
+	m.body.WriteString(assign)
+	return nil
+}
+
+// resultSignature renders the result list of a method
+func resultSignature(shape methodShape, targetType string) string {
+	switch {
+	case shape.update && shape.returnsError:
+		return "error"
+	case shape.update:
+		return ""
+	case shape.returnsError:
+		return "(" + targetType + ", error)"
 	}
+	return targetType
 }
 
 // writeImplStructToFile writes the implementation to a file
@@ -190,15 +613,26 @@ func writeImplStructToFile(implStruct string, outputDir string) error {
 		return err
 	}
 
+	// Format the generated source, fall back to the raw text so it can be inspected
+	content := []byte(implStruct)
+	formatted, formatErr := format.Source(content)
+	if formatErr == nil {
+		content = formatted
+	}
+
 	// Create file path
 	fileName := strings.ToLower(structName) + ".go"
 	filePath := filepath.Join(outputDir, fileName)
 
 	// Write to file
-	if err := os.WriteFile(filePath, []byte(implStruct), 0644); err != nil {
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
 		return fmt.Errorf("failed to write to file: %v", err)
 	}
 
+	if formatErr != nil {
+		return fmt.Errorf("generated code in %s is not valid Go: %v", filePath, formatErr)
+	}
+
 	fmt.Printf("Generated implementation file: %s\n", filePath)
 	return nil
 }
@@ -210,13 +644,14 @@ func extractPackageAndStructName(implStruct string) (packageName string, structN
 	for _, line := range lines {
 		if strings.HasPrefix(line, "package ") {
 			packageName = strings.TrimPrefix(line, "package ")
-		} else if strings.Contains(line, "type ") && strings.Contains(line, "Impl struct") {
-			parts := strings.Split(line, " ")
-			for i, part := range parts {
-				if part == "type" && i+1 < len(parts) {
-					structName = parts[i+1]
-					break
-				}
+		} else if strings.HasPrefix(line, "type ") && strings.HasSuffix(line, " struct {") {
+			// generic implementations declare type parameters after the name
+			name := strings.TrimPrefix(line, "type ")
+			if end := strings.IndexAny(name, "[ "); end != -1 {
+				name = name[:end]
+			}
+			if strings.HasSuffix(name, "Impl") {
+				structName = name
 			}
 		}
 		if packageName != "" && structName != "" {
@@ -228,12 +663,12 @@ func extractPackageAndStructName(implStruct string) (packageName string, structN
 }
 
 // ProcessInterface handles processing of an interface for code generation
-func ProcessInterface(iface InterfaceInfo, outputDir string) error {
+func ProcessInterface(loader *Loader, iface InterfaceInfo, outputDir string) error {
 	fmt.Printf("Processing interface: %s\n", iface.Name)
 
 	// Generate code
-	if err := GenerateCode(iface, outputDir); err != nil {
-		return fmt.Errorf("code generation failed: %v", err)
+	if err := GenerateCode(loader, iface, outputDir); err != nil {
+		return withContext(err, "code generation failed")
 	}
 
 	return nil
@@ -0,0 +1,169 @@
+package src
+
+import (
+	"flag"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of TestGenerate")
+
+// TestGenerate runs the generator over every module under testdata/generate.
+// Each case is copied into a temporary module, named example.com/<case>
+// unless the case brings its own go.mod; the generated files are compared
+// with the .golden files next to the inputs, generation errors with
+// errors.golden, and the module must pass go vet.
+func TestGenerate(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("testdata", "generate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range cases {
+		if !entry.IsDir() {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			caseDir := filepath.Join(root, entry.Name())
+			dir := t.TempDir()
+			copyCase(t, caseDir, dir)
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); os.IsNotExist(err) {
+				writeFiles(t, dir, map[string]string{"go.mod": "module example.com/" + entry.Name() + "\n\ngo 1.24\n"})
+			}
+			isolateGoEnv(t)
+			t.Chdir(dir)
+
+			generated, errs := generateModule(t, ".")
+			for name, content := range generated {
+				compareGolden(t, filepath.Join(caseDir, name+".golden"), content)
+			}
+			compareGolden(t, filepath.Join(caseDir, "errors.golden"), errs)
+			if errs != "" {
+				return
+			}
+
+			vet := exec.Command("go", "vet", "./...")
+			vet.Env = append(os.Environ(), "GOTOOLCHAIN=local", "GOPROXY=off")
+			if out, err := vet.CombinedOutput(); err != nil {
+				t.Errorf("generated code does not pass go vet: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+// copyCase copies the inputs of a case, leaving out the golden files
+func copyCase(t *testing.T, from, to string) {
+	t.Helper()
+	err := filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, ".golden") {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		writeFiles(t, to, map[string]string{filepath.ToSlash(rel): string(content)})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// generateModule generates every assembler found under dir next to its
+// interface. It returns the generated files by path and the generation
+// errors, one per line, with the positions relative to dir.
+func generateModule(t *testing.T, dir string) (map[string]string, string) {
+	t.Helper()
+	loader := NewLoader(BuildOptions{})
+
+	var errs []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".go" {
+			return err
+		}
+		if match, err := loader.MatchFile(path); err != nil || !match {
+			return err
+		}
+
+		interfaces, err := ParseFile(loader, path)
+		if err != nil {
+			errs = append(errs, err.Error())
+			return nil
+		}
+		for _, iface := range interfaces {
+			if err := ProcessInterface(loader, iface, filepath.Dir(path)); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated := make(map[string]string)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, "impl.go") {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		generated[filepath.ToSlash(path)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) == 0 {
+		return generated, ""
+	}
+	// positions in loaded packages carry absolute file names
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return generated, strings.ReplaceAll(strings.Join(errs, "\n")+"\n", abs+string(filepath.Separator), "")
+}
+
+// compareGolden compares content with a golden file, rewriting it with
+// -update. A missing golden file stands for empty content.
+func compareGolden(t *testing.T, golden string, content string) {
+	t.Helper()
+	if *update {
+		if content == "" {
+			if err := os.Remove(golden); err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			return
+		}
+		if err := os.WriteFile(golden, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if string(want) != content {
+		t.Errorf("%s differs from the generated output, run go test ./src -run TestGenerate -update to accept it\ngot:\n%s\nwant:\n%s",
+			filepath.Base(golden), content, want)
+	}
+}
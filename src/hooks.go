@@ -0,0 +1,279 @@
+package src
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hookInfo is a lifecycle hook written by hand on the implementation
+//
+//	// mapmap:before
+//	func (a *UserAssemblerImpl) trim(user *domain.User) error
+//
+//	// mapmap:after
+//	func (a *UserAssemblerImpl) fillAge(user domain.User, dto *dto.UserDTO)
+type hookInfo struct {
+	name         string         // method name
+	after        bool           // true for mapmap:after, false for mapmap:before
+	params       []string       // parameter types
+	returnsError bool           // the hook returns an error
+	position     token.Position // where the method is declared
+	scope        *TypeScope     // the file declaring the hook, its parameter types are written there
+}
+
+// findImplMethods finds the methods written by hand on the implementation in
+// the files of the output directory that satisfy the build constraints. It
+// returns the hooks in file and declaration order and the names of all
+// hand-written methods.
+func (l *Loader) findImplMethods(outputDir string, implName string) ([]hookInfo, map[string]bool, error) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the generated file is overwritten, hooks are never looked up in it
+	generatedFile := strings.ToLower(implName) + ".go"
+
+	// parameter types of hooks are resolved in the package of the implementation
+	var pkgPath string
+	if resolver, err := l.resolverFor(outputDir); err == nil {
+		pkgPath, _ = resolver.importPathOf(outputDir)
+	}
+
+	var hooks []hookInfo
+	written := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == generatedFile || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		filePath := filepath.Join(outputDir, entry.Name())
+		if match, err := l.build.matchFile(filePath); err != nil || !match {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse file %s: %v", filePath, err)
+		}
+
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+				continue
+			}
+			// receivers of generic implementations carry type parameters, as in *PageAssemblerImpl[S, T]
+			receiver, _, _ := strings.Cut(strings.TrimPrefix(exprToString(funcDecl.Recv.List[0].Type), "*"), "[")
+			if receiver != implName {
+				continue
+			}
+			written[funcDecl.Name.Name] = true
+			if funcDecl.Doc == nil {
+				continue
+			}
+
+			// look for the hook annotation
+			isHook, after := false, false
+			for _, comment := range funcDecl.Doc.List {
+				text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+				switch text {
+				case "mapmap:before":
+					isHook = true
+				case "mapmap:after":
+					isHook, after = true, true
+				}
+			}
+			if !isHook {
+				continue
+			}
+
+			hook := hookInfo{
+				name:     funcDecl.Name.Name,
+				after:    after,
+				position: fset.Position(funcDecl.Pos()),
+				scope:    &TypeScope{PackagePath: pkgPath, Imports: fileImports(file)},
+			}
+			for _, param := range parseFieldList(funcDecl.Type.Params) {
+				hook.params = append(hook.params, param.Type)
+			}
+
+			// hooks return nothing or an error
+			if funcDecl.Type.Results != nil {
+				results := parseFieldList(funcDecl.Type.Results)
+				if len(results) != 1 || results[0].Type != "error" {
+					return nil, nil, errorAt(hook.position, "hook %s may only return an error", hook.name)
+				}
+				hook.returnsError = true
+			}
+
+			hooks = append(hooks, hook)
+		}
+	}
+
+	return hooks, written, nil
+}
+
+// hookStatements renders the hook calls of a method. The order is: before
+// hooks of the implementation, BeforeMap of the source, the field mappings,
+// AfterMap of the target, after hooks of the implementation. Every hook may
+// take a context.Context first when the method has one.
+func (m *methodWriter) hookStatements(sourceType, targetType string, sourceParamType, targetResultType types.Type) (before, after string, err error) {
+	var beforeSb, afterSb strings.Builder
+	sourcePointer := strings.HasPrefix(sourceType, "*")
+	targetPointer := strings.HasPrefix(targetType, "*")
+
+	// before hooks take the source, or a pointer to the local copy of it
+	for _, hook := range m.g.hooks {
+		params, contextArg := m.hookParams(hook)
+		if hook.after || len(params) != 1 || !sameType(params[0], sourceType) {
+			continue
+		}
+		arg := hookArg(m.paramName, sourcePointer, params[0])
+		call, err := m.hookCall(fmt.Sprintf("a.%s(%s%s)", hook.name, contextArg, arg), hook.returnsError, hook.name)
+		if err != nil {
+			return "", "", err
+		}
+		beforeSb.WriteString(call)
+	}
+
+	// BeforeMap() on the source type
+	if method := lookupHookMethod(sourceParamType, "BeforeMap"); method != nil {
+		signature := method.Type().(*types.Signature)
+		contextArg, takesContext := m.methodContextArg(signature)
+		if params := signature.Params(); params.Len() != 0 && !(takesContext && params.Len() == 1) {
+			return "", "", errorAt(m.g.positionOf(method), "BeforeMap of %s must take no parameters, or a context.Context when the method has one", sourceType)
+		}
+		returnsError, err := hookReturnsError(signature)
+		if err != nil {
+			return "", "", errorAt(m.g.positionOf(method), "BeforeMap of %s: %v", sourceType, err)
+		}
+		call, err := m.hookCall(m.paramName+".BeforeMap("+strings.TrimSuffix(contextArg, ", ")+")", returnsError, "BeforeMap")
+		if err != nil {
+			return "", "", err
+		}
+		beforeSb.WriteString(call)
+	}
+
+	// AfterMap(src) on the target type
+	if method := lookupHookMethod(targetResultType, "AfterMap"); method != nil {
+		signature := method.Type().(*types.Signature)
+		contextArg, takesContext := m.methodContextArg(signature)
+		if params := signature.Params(); params.Len() != 1 && !(takesContext && params.Len() == 2) {
+			return "", "", errorAt(m.g.positionOf(method), "AfterMap of %s must take the source, after a context.Context when the method has one", targetType)
+		}
+		arg := m.paramName
+		paramType := signature.Params().At(signature.Params().Len() - 1).Type()
+		if !types.AssignableTo(sourceParamType, paramType) {
+			if !types.AssignableTo(types.NewPointer(sourceParamType), paramType) {
+				return "", "", errorAt(m.g.positionOf(method), "AfterMap of %s does not accept %s", targetType, sourceType)
+			}
+			arg = "&" + m.paramName
+		}
+		returnsError, err := hookReturnsError(signature)
+		if err != nil {
+			return "", "", errorAt(m.g.positionOf(method), "AfterMap of %s: %v", targetType, err)
+		}
+		call, err := m.hookCall("target.AfterMap("+contextArg+arg+")", returnsError, "AfterMap")
+		if err != nil {
+			return "", "", err
+		}
+		afterSb.WriteString(call)
+	}
+
+	// after hooks take the source and the target, or a pointer to it
+	for _, hook := range m.g.hooks {
+		params, contextArg := m.hookParams(hook)
+		if !hook.after || len(params) != 2 || !sameType(params[0], sourceType) || !sameType(params[1], targetType) {
+			continue
+		}
+		sourceArg := hookArg(m.paramName, sourcePointer, params[0])
+		targetArg := hookArg("target", targetPointer, params[1])
+		call, err := m.hookCall(fmt.Sprintf("a.%s(%s%s, %s)", hook.name, contextArg, sourceArg, targetArg), hook.returnsError, hook.name)
+		if err != nil {
+			return "", "", err
+		}
+		afterSb.WriteString(call)
+	}
+
+	return beforeSb.String(), afterSb.String(), nil
+}
+
+// hookParams strips a leading context.Context from the parameters of an
+// implementation hook. The hook only applies to methods with a context then,
+// which is passed as the returned argument prefix.
+func (m *methodWriter) hookParams(hook hookInfo) ([]string, string) {
+	params := hook.params
+	if len(params) == 0 || !m.g.isContextType(params[0], hook.scope) {
+		return params, ""
+	}
+	if m.contextName == "" {
+		return nil, ""
+	}
+
+	return params[1:], m.contextName + ", "
+}
+
+// methodContextArg returns the context argument prefix for a BeforeMap or
+// AfterMap method whose first parameter is a context.Context
+func (m *methodWriter) methodContextArg(signature *types.Signature) (string, bool) {
+	if signature.Params().Len() == 0 || !isContext(signature.Params().At(0).Type()) || m.contextName == "" {
+		return "", false
+	}
+
+	return m.contextName + ", ", true
+}
+
+// hookArg adapts a value or pointer variable to the parameter type of a hook
+func hookArg(name string, isPointer bool, paramType string) string {
+	paramIsPointer := strings.HasPrefix(paramType, "*")
+	switch {
+	case !isPointer && paramIsPointer:
+		return "&" + name
+	case isPointer && !paramIsPointer:
+		return "*" + name
+	}
+
+	return name
+}
+
+// hookCall renders a hook call, returning early when it fails
+func (m *methodWriter) hookCall(call string, returnsError bool, name string) (string, error) {
+	if !returnsError {
+		return "\t" + call + "\n", nil
+	}
+	if m.errReturn == "" {
+		return "", fmt.Errorf("hook %s returns an error but the method does not", name)
+	}
+
+	return fmt.Sprintf("\tif err := %s; err != nil {\n\t\t%s\n\t}\n", call, m.errReturn), nil
+}
+
+// lookupHookMethod finds a method on a type or a pointer to it
+func lookupHookMethod(t types.Type, name string) *types.Func {
+	if _, ok := types.Unalias(t).(*types.Pointer); !ok {
+		t = types.NewPointer(t)
+	}
+
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	method, _ := obj.(*types.Func)
+	return method
+}
+
+// hookReturnsError checks that a hook returns nothing or an error
+func hookReturnsError(signature *types.Signature) (bool, error) {
+	switch {
+	case signature.Results().Len() == 0:
+		return false, nil
+	case signature.Results().Len() == 1 && isError(signature.Results().At(0).Type()):
+		return true, nil
+	}
+
+	return false, fmt.Errorf("hooks may only return an error")
+}
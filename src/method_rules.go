@@ -0,0 +1,165 @@
+package src
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// resolveMethodConfig parses the comments of a method and merges in the rules
+// of the method it inherits from and of the method it is the inverse of.
+// Inverted rules override inherited ones, local rules override both.
+func (g *generator) resolveMethodConfig(method MethodInfo) (*MethodConfig, error) {
+	return g.doResolveMethodConfig(method, nil)
+}
+
+// doResolveMethodConfig resolves a method config, visiting holds the methods
+// on the current reference chain to detect cycles
+func (g *generator) doResolveMethodConfig(method MethodInfo, visiting []string) (*MethodConfig, error) {
+	if slices.Contains(visiting, method.Name) {
+		return nil, fmt.Errorf("method references form a cycle: %s -> %s", strings.Join(visiting, " -> "), method.Name)
+	}
+	visiting = append(visiting, method.Name)
+
+	config, err := parseMethodComments(method)
+	if err != nil {
+		return nil, err
+	}
+
+	var base []FieldRule
+	if inheritName := config.Options["inherit"]; inheritName != "" {
+		inheritMethod, err := g.referencedMethod(method, inheritName, "inherit")
+		if err != nil {
+			return nil, err
+		}
+
+		inheritConfig, err := g.doResolveMethodConfig(inheritMethod, visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rule := range inheritConfig.Rules {
+			if rule.origin == "" {
+				rule.origin = "inherited from " + inheritName
+			}
+			base = append(base, rule)
+		}
+	}
+
+	if inverseName := config.Options["inverse"]; inverseName != "" {
+		inverseMethod, err := g.referencedMethod(method, inverseName, "inverse")
+		if err != nil {
+			return nil, err
+		}
+		shape, err := g.shapeOf(method)
+		if err != nil {
+			return nil, err
+		}
+		inverseShape, err := g.shapeOf(inverseMethod)
+		if err != nil {
+			return nil, err
+		}
+		if !sameType(inverseShape.sourceType, shape.targetType) || !sameType(inverseShape.targetType, shape.sourceType) {
+			return nil, errorAt(method.Position, "method %s cannot be the inverse of %s: it maps %s to %s, expected %s to %s",
+				method.Name, inverseName, shape.sourceType, shape.targetType,
+				inverseShape.targetType, inverseShape.sourceType)
+		}
+
+		inverseConfig, err := g.doResolveMethodConfig(inverseMethod, visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		inverted, err := invertRules(method, config, inverseName, inverseConfig.Rules)
+		if err != nil {
+			return nil, err
+		}
+		base = mergeRules(base, inverted)
+	}
+
+	config.Rules = mergeRules(base, config.Rules)
+	return config, nil
+}
+
+// referencedMethod looks up the method named by an inverse or inherit option
+func (g *generator) referencedMethod(method MethodInfo, name string, option string) (MethodInfo, error) {
+	for _, candidate := range g.iface.Methods {
+		if candidate.Name == name {
+			return candidate, nil
+		}
+	}
+
+	return MethodInfo{}, errorAt(method.Position, "method %s: %s method %s does not exist in %s", method.Name, option, name, g.iface.Name)
+}
+
+// invertRules flips source and target of each rule of the inverse method.
+// Constants, expressions and source-less defaults cannot be inverted; the
+// method must then read that field itself or list it in ignoreSource.
+func invertRules(method MethodInfo, config *MethodConfig, inverseName string, rules []FieldRule) ([]FieldRule, error) {
+	overridden := make(map[string]bool)
+	for _, rule := range config.Rules {
+		if rule.Source != "" {
+			overridden[rule.Source] = true
+		}
+	}
+	for _, name := range config.Options.list("ignoreSource") {
+		overridden[name] = true
+	}
+
+	var inverted []FieldRule
+	for _, rule := range rules {
+		if rule.Ignore {
+			continue
+		}
+		if rule.Source == "" {
+			// rules that only set options are matched by name on both sides, a
+			// format applies both ways
+			if overridden[rule.Target] {
+				continue
+			}
+			if rule.Constant == "" && rule.Expression == "" {
+				if rule.Format != "" {
+					inverted = append(inverted, FieldRule{
+						Target: rule.Target,
+						Format: rule.Format,
+						origin: "inverted from " + inverseName,
+					})
+				}
+				continue
+			}
+			return nil, errorAt(method.Position, "method %s: rule for %s in %s uses a constant or expression and cannot be inverted; add a rule with source:%s or list it in ignoreSource",
+				method.Name, rule.Target, inverseName, rule.Target)
+		}
+
+		inverted = append(inverted, FieldRule{
+			Target: rule.Source,
+			Source: rule.Target,
+			Format: rule.Format,
+			origin: "inverted from " + inverseName,
+		})
+	}
+
+	return inverted, nil
+}
+
+// mergeRules overlays override on base by target field, keeping declaration order
+func mergeRules(base []FieldRule, override []FieldRule) []FieldRule {
+	overridden := make(map[string]bool)
+	for _, rule := range override {
+		overridden[rule.Target] = true
+	}
+
+	var merged []FieldRule
+	for _, rule := range base {
+		if !overridden[rule.Target] {
+			merged = append(merged, rule)
+		}
+	}
+
+	return append(merged, override...)
+}
+
+// sameType compares two type expressions ignoring pointers
+func sameType(a, b string) bool {
+	return strings.TrimPrefix(a, "*") == strings.TrimPrefix(b, "*")
+}
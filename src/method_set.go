@@ -0,0 +1,96 @@
+package src
+
+import (
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// dedupMethods implements each method once when embedded interfaces bring it
+// in several times. A method declared on the assembler itself wins over the
+// embedded ones; among embedded ones the first with mapmap comments does.
+// Methods sharing a name must have identical signatures, as in Go.
+func (g *generator) dedupMethods() error {
+	var methods []MethodInfo
+	index := make(map[string]int)
+	for _, method := range g.iface.Methods {
+		i, seen := index[method.Name]
+		if !seen {
+			index[method.Name] = len(methods)
+			methods = append(methods, method)
+			continue
+		}
+
+		kept := methods[i]
+		identical, err := g.sameSignature(kept, method)
+		if err != nil {
+			return err
+		}
+		if !identical {
+			return errorAt(method.Position, "method %s has a different signature than at %s", method.Name, kept.Position)
+		}
+
+		switch {
+		case kept.Scope == nil:
+		case method.Scope == nil:
+			methods[i] = method
+		case hasMapmapComment(kept) && hasMapmapComment(method) && !slices.Equal(kept.Comment, method.Comment):
+			return errorAt(method.Position, "method %s has different mapmap comments than at %s, declare it on %s to choose",
+				method.Name, kept.Position, g.iface.Name)
+		case hasMapmapComment(method):
+			methods[i] = method
+		}
+	}
+
+	g.iface.Methods = methods
+	return nil
+}
+
+// sameSignature reports whether two methods take and return identical types,
+// each resolved where it is declared
+func (g *generator) sameSignature(a, b MethodInfo) (bool, error) {
+	signatureA, err := g.signatureOf(a)
+	if err != nil {
+		return false, err
+	}
+	signatureB, err := g.signatureOf(b)
+	if err != nil {
+		return false, err
+	}
+	return types.Identical(signatureA, signatureB), nil
+}
+
+// signatureOf resolves the parameter and result types of a method
+func (g *generator) signatureOf(method MethodInfo) (*types.Signature, error) {
+	g.scope = method.Scope
+	defer func() { g.scope = nil }()
+
+	tuple := func(params []ParamInfo) (*types.Tuple, error) {
+		vars := make([]*types.Var, 0, len(params))
+		for _, param := range params {
+			t, err := g.resolveType(param.Type)
+			if err != nil {
+				return nil, errorAt(method.Position, "method %s: %v", method.Name, err)
+			}
+			vars = append(vars, types.NewParam(0, nil, param.Name, t))
+		}
+		return types.NewTuple(vars...), nil
+	}
+
+	params, err := tuple(method.Params)
+	if err != nil {
+		return nil, err
+	}
+	results, err := tuple(method.Results)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewSignatureType(nil, nil, nil, params, results, false), nil
+}
+
+// hasMapmapComment reports whether a method carries mapping rules or options
+func hasMapmapComment(method MethodInfo) bool {
+	return slices.ContainsFunc(method.Comment, func(comment string) bool {
+		return strings.Contains(comment, "mapmap:")
+	})
+}
@@ -0,0 +1,435 @@
+package src
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// moduleResolver finds the directory of a package under the module rules of
+// the go command, without going to the network: the standard library found
+// in GOROOT, then go.work and the main module, replace, vendor and finally
+// the module cache
+type moduleResolver struct {
+	goroot    string            // GOROOT
+	modCache  string            // module cache directory
+	modules   []moduleRoot      // the main module and the modules used by go.work, longest path first
+	replaces  map[string]string // module path → local directory or "path@version" replacing it
+	requires  map[string]string // module path → required version
+	vendorDir string            // the vendor directory when vendoring is enabled
+}
+
+// moduleRoot is a module on disk
+type moduleRoot struct {
+	path string // module path
+	dir  string // module root directory
+}
+
+// modFile is the content of a go.mod or go.work file
+type modFile struct {
+	module   string            // module declaration
+	goVer    string            // go version
+	uses     []string          // use directories of go.work
+	requires map[string]string // required modules and their versions
+	replaces map[string]string // replacement targets, a local directory or "path@version"
+}
+
+// newModuleResolver creates the resolver of the module a directory belongs to
+func newModuleResolver(dir string) (*moduleResolver, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &moduleResolver{
+		goroot:   build.Default.GOROOT,
+		modCache: moduleCacheDir(),
+		replaces: make(map[string]string),
+		requires: make(map[string]string),
+	}
+
+	modDir := findUp(absDir, "go.mod")
+	if modDir == "" {
+		// outside a module only the standard library loads
+		return r, nil
+	}
+
+	// the modules of go.work are all main modules
+	workDir := ""
+	if gowork := os.Getenv("GOWORK"); gowork != "off" {
+		if gowork != "" {
+			workDir = filepath.Dir(gowork)
+		} else {
+			workDir = findUp(absDir, "go.work")
+		}
+	}
+
+	moduleDirs := []string{modDir}
+	var work *modFile
+	if workDir != "" {
+		work, err = readModFile(filepath.Join(workDir, "go.work"))
+		if err != nil {
+			return nil, err
+		}
+		moduleDirs = nil
+		for _, use := range work.uses {
+			moduleDirs = append(moduleDirs, filepath.Join(workDir, use))
+		}
+	}
+
+	for _, moduleDir := range moduleDirs {
+		mod, err := readModFile(filepath.Join(moduleDir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		if mod.module == "" {
+			return nil, fmt.Errorf("%s has no module declaration", filepath.Join(moduleDir, "go.mod"))
+		}
+		r.modules = append(r.modules, moduleRoot{path: mod.module, dir: moduleDir})
+		for modulePath, version := range mod.requires {
+			if current, ok := r.requires[modulePath]; !ok || compareVersion(version, current) > 0 {
+				r.requires[modulePath] = version
+			}
+		}
+		r.addReplaces(mod.replaces, moduleDir)
+
+		// a single module at go 1.14 or later uses vendor by default when vendor/modules.txt exists
+		if workDir == "" && vendorEnabled(moduleDir, mod.goVer) {
+			r.vendorDir = filepath.Join(moduleDir, "vendor")
+		}
+	}
+
+	// the replaces of go.work win over those of the modules
+	if work != nil {
+		r.addReplaces(work.replaces, workDir)
+	}
+
+	sort.Slice(r.modules, func(i, j int) bool {
+		return len(r.modules[i].path) > len(r.modules[j].path)
+	})
+
+	return r, nil
+}
+
+// addReplaces records replaces, relative directories are relative to the
+// directory of the go.mod or go.work file
+func (r *moduleResolver) addReplaces(replaces map[string]string, baseDir string) {
+	for modulePath, target := range replaces {
+		if isLocalPath(target) && !filepath.IsAbs(target) {
+			target = filepath.Join(baseDir, target)
+		}
+		r.replaces[modulePath] = target
+	}
+}
+
+// packageDir finds the directory of a package. srcDir is the directory of the
+// importing package, standard library packages find their vendored imports
+// through it.
+func (r *moduleResolver) packageDir(importPath string, srcDir string) (string, error) {
+	if importPath == "" || strings.HasPrefix(importPath, ".") || filepath.IsAbs(importPath) {
+		return "", fmt.Errorf("unsupported import path %q", importPath)
+	}
+
+	// the standard library and its vendored packages. Module paths may have no
+	// dot either, as in module myapp, so only a directory that exists in GOROOT
+	// is taken as the standard library
+	gorootSrc := filepath.Join(r.goroot, "src")
+	if isStandardPath(importPath) {
+		if dir, err := existingDir(filepath.Join(gorootSrc, filepath.FromSlash(importPath)), importPath); err == nil {
+			return dir, nil
+		}
+	}
+	if srcDir != "" && isWithin(srcDir, gorootSrc) {
+		if dir, err := existingDir(filepath.Join(gorootSrc, "vendor", filepath.FromSlash(importPath)), importPath); err == nil {
+			return dir, nil
+		}
+	}
+
+	// the main module and the modules of go.work
+	for _, module := range r.modules {
+		if rest, ok := cutModulePath(importPath, module.path); ok {
+			return existingDir(filepath.Join(module.dir, filepath.FromSlash(rest)), importPath)
+		}
+	}
+
+	// dependencies: replace first, then vendor, finally the module cache
+	modulePath, version := r.requiredModule(importPath)
+	if target, ok := r.replaces[modulePath]; ok {
+		rest, _ := cutModulePath(importPath, modulePath)
+		if isLocalPath(target) {
+			return existingDir(filepath.Join(target, filepath.FromSlash(rest)), importPath)
+		}
+		replacePath, replaceVersion, _ := strings.Cut(target, "@")
+		return existingDir(filepath.Join(r.modCacheDir(replacePath, replaceVersion), filepath.FromSlash(rest)), importPath)
+	}
+	if r.vendorDir != "" {
+		return existingDir(filepath.Join(r.vendorDir, filepath.FromSlash(importPath)), importPath)
+	}
+	if modulePath == "" && isStandardPath(importPath) {
+		return existingDir(filepath.Join(gorootSrc, filepath.FromSlash(importPath)), importPath)
+	}
+	if modulePath == "" {
+		return "", fmt.Errorf("package %s is not provided by any required module", importPath)
+	}
+
+	rest, _ := cutModulePath(importPath, modulePath)
+	return existingDir(filepath.Join(r.modCacheDir(modulePath, version), filepath.FromSlash(rest)), importPath)
+}
+
+// requiredModule finds the required module providing an import path, the
+// longest module path wins
+func (r *moduleResolver) requiredModule(importPath string) (string, string) {
+	var found, version string
+	for modulePath, modVersion := range r.requires {
+		if _, ok := cutModulePath(importPath, modulePath); ok && len(modulePath) > len(found) {
+			found, version = modulePath, modVersion
+		}
+	}
+	// modules that only appear in a replace
+	for modulePath := range r.replaces {
+		if _, ok := cutModulePath(importPath, modulePath); ok && len(modulePath) > len(found) {
+			found, version = modulePath, ""
+		}
+	}
+
+	return found, version
+}
+
+// modCacheDir is the directory of a module in the module cache
+func (r *moduleResolver) modCacheDir(modulePath, version string) string {
+	return filepath.Join(r.modCache, filepath.FromSlash(escapeModulePath(modulePath))+"@"+escapeModulePath(version))
+}
+
+// importPathOf returns the import path of a directory in the main module or
+// a module of go.work
+func (r *moduleResolver) importPathOf(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, module := range r.modules {
+		if !isWithin(absDir, module.dir) {
+			continue
+		}
+		rel, err := filepath.Rel(module.dir, absDir)
+		if err != nil {
+			return "", err
+		}
+		if rel == "." {
+			return module.path, nil
+		}
+		return module.path + "/" + filepath.ToSlash(rel), nil
+	}
+
+	return "", fmt.Errorf("directory %s is not in any module", dir)
+}
+
+// readModFile reads a go.mod or go.work file
+func readModFile(filePath string) (*modFile, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	mod := &modFile{
+		requires: make(map[string]string),
+		replaces: make(map[string]string),
+	}
+
+	block := ""
+	for line := range strings.SplitSeq(string(content), "\n") {
+		if index := strings.Index(line, "//"); index != -1 {
+			line = line[:index]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// block form: require ( ... )
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			mod.addDirective(block, fields)
+			continue
+		}
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		mod.addDirective(fields[0], fields[1:])
+	}
+
+	return mod, nil
+}
+
+// addDirective records a directive of a go.mod or go.work file
+func (mod *modFile) addDirective(verb string, args []string) {
+	for i := range args {
+		args[i] = strings.Trim(args[i], "\"`")
+	}
+
+	switch verb {
+	case "module":
+		if len(args) > 0 {
+			mod.module = args[0]
+		}
+	case "go":
+		if len(args) > 0 {
+			mod.goVer = args[0]
+		}
+	case "use":
+		if len(args) > 0 {
+			mod.uses = append(mod.uses, args[0])
+		}
+	case "require":
+		if len(args) >= 2 {
+			mod.requires[args[0]] = args[1]
+		}
+	case "replace":
+		// old [version] => new [version]
+		arrow := -1
+		for i, arg := range args {
+			if arg == "=>" {
+				arrow = i
+			}
+		}
+		if arrow < 1 || arrow+1 >= len(args) {
+			return
+		}
+		target := args[arrow+1]
+		if arrow+2 < len(args) {
+			target += "@" + args[arrow+2]
+		}
+		mod.replaces[args[0]] = target
+	}
+}
+
+// vendorEnabled reports whether the vendor directory of a module is used
+func vendorEnabled(moduleDir string, goVer string) bool {
+	if _, err := os.Stat(filepath.Join(moduleDir, "vendor", "modules.txt")); err != nil {
+		return false
+	}
+
+	for flag := range strings.FieldsSeq(os.Getenv("GOFLAGS")) {
+		switch flag {
+		case "-mod=vendor":
+			return true
+		case "-mod=mod", "-mod=readonly":
+			return false
+		}
+	}
+
+	return goVer != "" && compareVersion(goVer, "1.14") >= 0
+}
+
+// compareVersion compares the numeric parts of go or module versions, so
+// 1.9 < 1.14 and v1.2.0 < v1.10.0
+func compareVersion(a, b string) int {
+	a, _, _ = strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	b, _, _ = strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			fmt.Sscan(aParts[i], &x)
+		}
+		if i < len(bParts) {
+			fmt.Sscan(bParts[i], &y)
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// moduleCacheDir returns GOMODCACHE, or else GOPATH/pkg/mod
+func moduleCacheDir() string {
+	if modCache := os.Getenv("GOMODCACHE"); modCache != "" {
+		return modCache
+	}
+
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// escapeModulePath escapes a path for the module cache, writing upper case
+// letters as ! followed by the lower case letter
+func escapeModulePath(modulePath string) string {
+	var sb strings.Builder
+	for _, r := range modulePath {
+		if unicode.IsUpper(r) {
+			sb.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// cutModulePath reports whether an import path is within a module and
+// returns its path relative to the module
+func cutModulePath(importPath, modulePath string) (string, bool) {
+	if importPath == modulePath {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(importPath, modulePath+"/")
+	return rest, ok
+}
+
+// isStandardPath reports whether the first element of an import path has no
+// dot, as in the standard library
+func isStandardPath(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// isLocalPath reports whether a replacement target is a local directory
+func isLocalPath(target string) bool {
+	return strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") || filepath.IsAbs(target) ||
+		target == "." || target == ".."
+}
+
+// isWithin reports whether dir is root or below it
+func isWithin(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// findUp walks up from dir to the first directory containing the named file
+func findUp(dir string, name string) string {
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, name)); err == nil {
+			return current
+		}
+		if filepath.Dir(current) == current {
+			return ""
+		}
+	}
+}
+
+// existingDir checks that the directory of a package exists
+func existingDir(dir string, importPath string) (string, error) {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("package %s not found (%s)", importPath, dir)
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,250 @@
+package src
+
+import (
+	"go/build"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files under root, creating parent directories as needed
+func writeFiles(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// isolateGoEnv keeps the environment of the test run out of module resolution
+func isolateGoEnv(t testing.TB) string {
+	t.Helper()
+	modCache := t.TempDir()
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOMODCACHE", modCache)
+	return modCache
+}
+
+func TestReadModFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": `// leading comment
+module example.com/app // trailing comment
+
+go 1.22
+
+require example.com/single v1.0.0
+
+require (
+	example.com/a v1.2.0
+	example.com/b v0.3.1 // indirect
+)
+
+replace example.com/a => ../a
+
+replace (
+	example.com/b v0.3.1 => example.com/fork v0.4.0
+	"example.com/quoted" => ./quoted
+)
+`,
+		"go.work": `go 1.22
+
+use ./app
+use (
+	./lib
+	../shared
+)
+
+replace example.com/a => ./a
+`,
+	})
+
+	mod, err := readModFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.module != "example.com/app" {
+		t.Errorf("module = %q, want example.com/app", mod.module)
+	}
+	if mod.goVer != "1.22" {
+		t.Errorf("go = %q, want 1.22", mod.goVer)
+	}
+	wantRequires := map[string]string{
+		"example.com/single": "v1.0.0",
+		"example.com/a":      "v1.2.0",
+		"example.com/b":      "v0.3.1",
+	}
+	if !maps.Equal(mod.requires, wantRequires) {
+		t.Errorf("requires = %v, want %v", mod.requires, wantRequires)
+	}
+	wantReplaces := map[string]string{
+		"example.com/a":      "../a",
+		"example.com/b":      "example.com/fork@v0.4.0",
+		"example.com/quoted": "./quoted",
+	}
+	if !maps.Equal(mod.replaces, wantReplaces) {
+		t.Errorf("replaces = %v, want %v", mod.replaces, wantReplaces)
+	}
+
+	work, err := readModFile(filepath.Join(dir, "go.work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"./app", "./lib", "../shared"}; !slices.Equal(work.uses, want) {
+		t.Errorf("uses = %v, want %v", work.uses, want)
+	}
+	if work.replaces["example.com/a"] != "./a" {
+		t.Errorf("work replace = %q, want ./a", work.replaces["example.com/a"])
+	}
+
+	if _, err := readModFile(filepath.Join(dir, "missing.mod")); err == nil {
+		t.Error("reading a missing file succeeded")
+	}
+}
+
+func TestAddDirective(t *testing.T) {
+	tests := []struct {
+		name     string
+		verb     string
+		args     []string
+		module   string
+		goVer    string
+		uses     []string
+		requires map[string]string
+		replaces map[string]string
+	}{
+		{name: "module", verb: "module", args: []string{"example.com/m"}, module: "example.com/m"},
+		{name: "quoted module", verb: "module", args: []string{`"example.com/m"`}, module: "example.com/m"},
+		{name: "module without path", verb: "module"},
+		{name: "go", verb: "go", args: []string{"1.21"}, goVer: "1.21"},
+		{name: "use", verb: "use", args: []string{"./lib"}, uses: []string{"./lib"}},
+		{name: "require", verb: "require", args: []string{"example.com/a", "v1.0.0"},
+			requires: map[string]string{"example.com/a": "v1.0.0"}},
+		{name: "require without version", verb: "require", args: []string{"example.com/a"}},
+		{name: "replace with directory", verb: "replace", args: []string{"example.com/a", "=>", "../a"},
+			replaces: map[string]string{"example.com/a": "../a"}},
+		{name: "replace version with module", verb: "replace", args: []string{"example.com/a", "v1.0.0", "=>", "example.com/b", "v1.1.0"},
+			replaces: map[string]string{"example.com/a": "example.com/b@v1.1.0"}},
+		{name: "replace without arrow", verb: "replace", args: []string{"example.com/a", "../a"}},
+		{name: "replace without target", verb: "replace", args: []string{"example.com/a", "=>"}},
+		{name: "unknown verb", verb: "toolchain", args: []string{"go1.22.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := &modFile{requires: make(map[string]string), replaces: make(map[string]string)}
+			mod.addDirective(tt.verb, tt.args)
+
+			if mod.module != tt.module {
+				t.Errorf("module = %q, want %q", mod.module, tt.module)
+			}
+			if mod.goVer != tt.goVer {
+				t.Errorf("go = %q, want %q", mod.goVer, tt.goVer)
+			}
+			if !slices.Equal(mod.uses, tt.uses) {
+				t.Errorf("uses = %v, want %v", mod.uses, tt.uses)
+			}
+			if !maps.Equal(mod.requires, tt.requires) && !(len(mod.requires) == 0 && len(tt.requires) == 0) {
+				t.Errorf("requires = %v, want %v", mod.requires, tt.requires)
+			}
+			if !maps.Equal(mod.replaces, tt.replaces) && !(len(mod.replaces) == 0 && len(tt.replaces) == 0) {
+				t.Errorf("replaces = %v, want %v", mod.replaces, tt.replaces)
+			}
+		})
+	}
+}
+
+func TestPackageDir(t *testing.T) {
+	modCache := isolateGoEnv(t)
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/go.mod": `module myapp
+
+go 1.22
+
+require (
+	example.com/cached v1.2.0
+	example.com/replaced v1.0.0
+	example.com/forked v1.0.0
+)
+
+replace example.com/replaced => ../replaced
+replace example.com/forked => example.com/Fork v1.5.0
+`,
+		"app/domain/user.go":                     "package domain\n",
+		"replaced/go.mod":                        "module example.com/replaced\n",
+		"replaced/sub/sub.go":                    "package sub\n",
+		"plain/go.mod":                           "module plain\n",
+		"plain/domain/user.go":                   "package domain\n",
+		"work/go.work":                           "go 1.22\n\nuse (\n\t./a\n\t./b\n)\n",
+		"work/a/go.mod":                          "module example.com/a\n",
+		"work/a/x/x.go":                          "package x\n",
+		"work/b/go.mod":                          "module example.com/a/b\n",
+		"work/b/y/y.go":                          "package y\n",
+		"vendored/go.mod":                        "module example.com/vendored\n\ngo 1.22\n\nrequire example.com/dep v1.0.0\n",
+		"vendored/vendor/modules.txt":            "# example.com/dep v1.0.0\n",
+		"vendored/vendor/example.com/dep/dep.go": "package dep\n",
+	})
+	writeFiles(t, modCache, map[string]string{
+		"example.com/cached@v1.2.0/pkg/pkg.go": "package pkg\n",
+		"example.com/!fork@v1.5.0/f/f.go":      "package f\n",
+	})
+	goroot := filepath.Join(build.Default.GOROOT, "src")
+
+	tests := []struct {
+		name       string
+		moduleDir  string
+		importPath string
+		want       string // relative to root, the module cache or GOROOT/src
+		base       string
+		wantErr    string
+	}{
+		{name: "standard library", moduleDir: "app", importPath: "fmt", base: goroot, want: "fmt"},
+		{name: "nested standard library", moduleDir: "app", importPath: "go/types", base: goroot, want: "go/types"},
+		{name: "main module without dot", moduleDir: "app", importPath: "myapp/domain", base: root, want: "app/domain"},
+		{name: "main module root", moduleDir: "app", importPath: "myapp", base: root, want: "app"},
+		{name: "local replace", moduleDir: "app", importPath: "example.com/replaced/sub", base: root, want: "replaced/sub"},
+		{name: "module replace", moduleDir: "app", importPath: "example.com/forked/f", base: modCache, want: "example.com/!fork@v1.5.0/f"},
+		{name: "module cache", moduleDir: "app", importPath: "example.com/cached/pkg", base: modCache, want: "example.com/cached@v1.2.0/pkg"},
+		{name: "dotless module of another main module", moduleDir: "plain", importPath: "plain/domain", base: root, want: "plain/domain"},
+		{name: "workspace module", moduleDir: "work/a", importPath: "example.com/a/x", base: root, want: "work/a/x"},
+		{name: "longest workspace module wins", moduleDir: "work/a", importPath: "example.com/a/b/y", base: root, want: "work/b/y"},
+		{name: "vendor", moduleDir: "vendored", importPath: "example.com/dep", base: root, want: "vendored/vendor/example.com/dep"},
+		{name: "missing package of main module", moduleDir: "app", importPath: "myapp/missing", wantErr: "myapp/missing"},
+		{name: "missing dotless package", moduleDir: "app", importPath: "nosuchpkg/x", wantErr: "nosuchpkg/x"},
+		{name: "unknown module", moduleDir: "app", importPath: "example.com/unknown", wantErr: "example.com/unknown"},
+		{name: "relative import", moduleDir: "app", importPath: "./domain", wantErr: "./domain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newModuleResolver(filepath.Join(root, filepath.FromSlash(tt.moduleDir)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir, err := r.packageDir(tt.importPath, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("packageDir(%q) = %q, %v, want error mentioning %q", tt.importPath, dir, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("packageDir(%q) failed: %v", tt.importPath, err)
+			}
+			if want := filepath.Join(tt.base, filepath.FromSlash(tt.want)); dir != want {
+				t.Errorf("packageDir(%q) = %q, want %q", tt.importPath, dir, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package src
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strings"
+)
+
+// matchKeys maps a matching strategy to the key it compares; fields whose keys
+// are equal match. An empty key never matches.
+var matchKeys = map[string]func(name string, tag string) string{
+	// exact field names
+	"exact": func(name string, tag string) string {
+		return name
+	},
+	// UserID <-> UserId
+	"ignoreCase": func(name string, tag string) string {
+		return strings.ToLower(name)
+	},
+	// CreatedAt <-> Created_At <-> created_at
+	"snake": normalizeName,
+	// field names or json tag names, compared like snake
+	"json": func(name string, tag string) string {
+		return normalizeName(tagName(tag, "json", name), "")
+	},
+	// field names or db tag names, compared like snake
+	"db": func(name string, tag string) string {
+		return normalizeName(tagName(tag, "db", name), "")
+	},
+}
+
+// nameMatcher finds the source field of a target field that has no explicit rule
+type nameMatcher struct {
+	strategies []string // strategies tried in order after the exact field name
+	prefixes   []string // prefixes stripped before comparing names
+	suffixes   []string // suffixes stripped before comparing names
+}
+
+// newNameMatcher builds a matcher from the match, stripPrefix and stripSuffix options
+func newNameMatcher(options Options) (*nameMatcher, error) {
+	nm := &nameMatcher{
+		strategies: options.list("match"),
+		prefixes:   options.list("stripPrefix"),
+		suffixes:   options.list("stripSuffix"),
+	}
+
+	for _, strategy := range nm.strategies {
+		if _, ok := matchKeys[strategy]; !ok {
+			return nil, fmt.Errorf("unknown match strategy %q", strategy)
+		}
+	}
+
+	// stripping alone still compares exact names
+	if len(nm.strategies) == 0 && (len(nm.prefixes) > 0 || len(nm.suffixes) > 0) {
+		nm.strategies = []string{"exact"}
+	}
+
+	return nm, nil
+}
+
+// match returns the name of the source field matching the target field, or
+// an empty string. Source fields in ignoredSources never match. Two source
+// fields matching with the same strategy is an error.
+func (nm *nameMatcher) match(targetName, targetTag string, sourceStruct *types.Struct, ignoredSources map[string]bool) (string, error) {
+	// exact names always win
+	if field := findField(sourceStruct, targetName); field != nil && !ignoredSources[targetName] {
+		return targetName, nil
+	}
+
+	for _, strategy := range nm.strategies {
+		keyOf := matchKeys[strategy]
+		targetKey := keyOf(nm.strip(targetName), targetTag)
+		if targetKey == "" {
+			continue
+		}
+
+		var candidates []string
+		for i := range sourceStruct.NumFields() {
+			sourceName := sourceStruct.Field(i).Name()
+			if ignoredSources[sourceName] {
+				continue
+			}
+			if keyOf(nm.strip(sourceName), sourceStruct.Tag(i)) == targetKey {
+				candidates = append(candidates, sourceName)
+			}
+		}
+
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return candidates[0], nil
+		default:
+			return "", fmt.Errorf("target field %s is ambiguous with match strategy %s: source fields %s all match",
+				targetName, strategy, strings.Join(candidates, ", "))
+		}
+	}
+
+	return "", nil
+}
+
+// strip removes the first configured prefix and suffix found on a name
+func (nm *nameMatcher) strip(name string) string {
+	for _, prefix := range nm.prefixes {
+		if stripped, ok := strings.CutPrefix(name, prefix); ok && stripped != "" {
+			name = stripped
+			break
+		}
+	}
+	for _, suffix := range nm.suffixes {
+		if stripped, ok := strings.CutSuffix(name, suffix); ok && stripped != "" {
+			name = stripped
+			break
+		}
+	}
+
+	return name
+}
+
+// normalizeName drops underscores and case so snake and camel case names compare equal
+func normalizeName(name string, tag string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// tagName returns the name part of a struct tag, or fallback when the tag is absent
+func tagName(tag string, key string, fallback string) string {
+	value, ok := reflect.StructTag(tag).Lookup(key)
+	if !ok {
+		return fallback
+	}
+
+	name, _, _ := strings.Cut(value, ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return fallback
+	}
+
+	return name
+}
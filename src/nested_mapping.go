@@ -0,0 +1,296 @@
+package src
+
+import (
+	"fmt"
+	"go/types"
+	"maps"
+	"strings"
+)
+
+// nestedHelper is a method of the implementation mapping one nested type to
+// another, or deep copying a recursive type. Helpers are registered before
+// their body is generated, so a type that contains itself calls the helper
+// it is part of instead of being expanded forever.
+type nestedHelper struct {
+	name         string
+	source       types.Type
+	target       types.Type
+	copy         bool       // deep copies source, target is the same type
+	hasContext   bool       // takes the context of the method that needs it
+	returnsError bool       // returns an error like the method that needs it
+	options      Options    // mapping options of the method that needs it, nil for copy helpers
+	optionScope  *TypeScope // where the subtype option is written, nil for the interface file
+	origin       MethodInfo // interface method the helper was first needed by
+}
+
+// typePair is a source and target type being assigned
+type typePair struct {
+	source types.Type
+	target types.Type
+}
+
+// helperFor returns the helper for a pair of types, registering it when no
+// helper with the same context, error result, options and embedded assembler
+// exists yet. Copy helpers only need the same embedded assembler. Unnamed
+// types are named after the field being assigned.
+func (m *methodWriter) helperFor(sourceType, targetType types.Type, name string, copy bool) *nestedHelper {
+	hasContext := m.contextName != "" && !copy
+	returnsError := m.errReturn != "" && !copy
+	var options Options
+	var optionScope *TypeScope
+	if !copy {
+		optionScope = m.optionScope
+		options = helperOptions(m.options)
+		// the field being assigned decides, a field rule may deep copy a field
+		// of a method that does not, or the reverse
+		delete(options, "deepCopy")
+		if m.deepCopy {
+			options["deepCopy"] = "true"
+		}
+	}
+	for _, helper := range m.g.helpers {
+		if helper.copy == copy && helper.hasContext == hasContext && helper.returnsError == returnsError &&
+			maps.Equal(helper.options, options) && helper.optionScope == optionScope &&
+			helper.origin.Assembler == m.origin.Assembler &&
+			types.Identical(helper.source, sourceType) && types.Identical(helper.target, targetType) {
+			return helper
+		}
+	}
+
+	helperName := "copy" + typeBaseName(sourceType, name)
+	if !copy {
+		helperName = "map" + typeBaseName(sourceType, name) + "To" + typeBaseName(targetType, name)
+	}
+	base := helperName
+	for i := 2; m.g.helperNameTaken(helperName); i++ {
+		helperName = fmt.Sprintf("%s%d", base, i)
+	}
+
+	helper := &nestedHelper{
+		name:         helperName,
+		source:       sourceType,
+		target:       targetType,
+		copy:         copy,
+		hasContext:   hasContext,
+		returnsError: returnsError,
+		options:      options,
+		optionScope:  optionScope,
+		origin:       m.origin,
+	}
+	m.g.helpers = append(m.g.helpers, helper)
+	return helper
+}
+
+// helperNameTaken reports whether a helper or interface method uses a name
+func (g *generator) helperNameTaken(name string) bool {
+	for _, helper := range g.helpers {
+		if helper.name == name {
+			return true
+		}
+	}
+	for _, method := range g.iface.Methods {
+		if method.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// helperOptions keeps the options of a method that apply to the nested types
+// it maps. ignoreSource names fields of the method's own source, and the
+// method options such as inverse or constructor only concern the method.
+func helperOptions(options Options) Options {
+	kept := make(Options)
+	for key, value := range options {
+		if mappingOptionKeys[key] && key != "ignoreSource" {
+			kept[key] = value
+		}
+	}
+	return kept
+}
+
+// typeBaseName names a type in a helper name: the name of a named type, or
+// else the fallback, usually the field holding a value of the type
+func typeBaseName(t types.Type, fallback string) string {
+	if named, ok := types.Unalias(t).(*types.Named); ok {
+		return upperFirst(named.Obj().Name())
+	}
+	return upperFirst(fallback)
+}
+
+// assignNested renders a call to the interface method declared for a nested
+// struct, or any other named type that refers back to itself, so its rules
+// apply. Without one a helper maps it by name.
+func (m *methodWriter) assignNested(targetExpr, sourceExpr, name string, sourceType, targetType types.Type) (string, error) {
+	method, err := pickConverter(m.g.methods, nil, sourceType, targetType, m.contextName != "")
+	if err != nil {
+		return "", err
+	}
+	if method != nil {
+		return m.assignConverted(targetExpr, sourceExpr, name, method)
+	}
+
+	helper := m.helperFor(sourceType, targetType, name, false)
+	return m.assignConverted(targetExpr, sourceExpr, name, &converter{
+		receiver:     "a",
+		name:         helper.name,
+		source:       sourceType,
+		target:       targetType,
+		returnsError: helper.returnsError,
+		takesContext: helper.hasContext,
+	})
+}
+
+// enterPair records a pair of types being assigned and reports whether it is
+// already being assigned further out, meaning the types are recursive
+func (m *methodWriter) enterPair(sourceType, targetType types.Type) bool {
+	for _, pair := range m.pairStack {
+		if types.Identical(pair.source, sourceType) && types.Identical(pair.target, targetType) {
+			return true
+		}
+	}
+	m.pairStack = append(m.pairStack, typePair{source: sourceType, target: targetType})
+	return false
+}
+
+// leavePair removes the innermost pair recorded by enterPair
+func (m *methodWriter) leavePair() {
+	m.pairStack = m.pairStack[:len(m.pairStack)-1]
+}
+
+// cycleError explains a recursive pair of types that cannot be mapped
+// through a helper, listing the types on the cycle
+func (m *methodWriter) cycleError(sourceType, targetType types.Type) error {
+	var path []string
+	inCycle := false
+	for _, pair := range m.pairStack {
+		if types.Identical(pair.source, sourceType) && types.Identical(pair.target, targetType) {
+			inCycle = true
+		}
+		if inCycle {
+			path = append(path, fmt.Sprintf("%s -> %s", pair.source, pair.target))
+		}
+	}
+	path = append(path, fmt.Sprintf("%s -> %s", sourceType, targetType))
+
+	return fmt.Errorf("cannot map recursive types %s to %s, the cycle %s does not pass through a pair of named types",
+		sourceType, targetType, strings.Join(path, ", "))
+}
+
+// generateHelpers renders every registered helper. Generating a helper may
+// register further helpers, which are rendered in turn.
+func (g *generator) generateHelpers() (string, error) {
+	sb := strings.Builder{}
+	for i := 0; i < len(g.helpers); i++ {
+		helper := g.helpers[i]
+		code, err := g.generateHelper(helper)
+		if err != nil {
+			return "", atPosition(helper.origin.Position, withContext(err, "method %s needs %s from %s to %s",
+				helper.origin.Name, helper.name, helper.source, helper.target))
+		}
+		sb.WriteString(code)
+	}
+
+	return sb.String(), nil
+}
+
+// generateHelper renders one helper. Mapping helpers match fields by name
+// under the options of the method needing them; copy helpers copy every
+// reachable field.
+func (g *generator) generateHelper(helper *nestedHelper) (string, error) {
+	m := &methodWriter{
+		g:           g,
+		paramName:   "src",
+		sourceType:  helper.source,
+		origin:      helper.origin,
+		optionScope: helper.optionScope,
+	}
+	if err := m.applyOptions(helper.options); err != nil {
+		return "", err
+	}
+
+	sourceType := g.typeString(helper.source)
+	targetType := g.typeString(helper.target)
+	params := "src " + sourceType
+	if helper.hasContext {
+		m.contextName = "ctx"
+		params = "ctx " + g.addImport("context", "context") + ".Context, " + params
+	}
+	results, finalReturn := targetType, "return target"
+	if helper.returnsError {
+		m.errReturn = "return " + zeroValue(helper.target, g.typeString) + ", err"
+		results, finalReturn = "("+targetType+", error)", "return target, nil"
+	}
+
+	description := "maps " + sourceType + " to " + targetType
+	switch {
+	case helper.copy:
+		description = "deep copies " + sourceType
+		m.deepCopy = true
+		copied, err := m.copyExpanded("target", "src", typeBaseName(helper.source, "Value"), helper.source)
+		if err != nil {
+			return "", err
+		}
+		m.body.WriteString(copied)
+
+	case isStruct(helper.source) && isStruct(helper.target):
+		if err := m.writeNestedFields(helper); err != nil {
+			return "", err
+		}
+
+	default:
+		assign, err := m.assignValue("target", "src", typeBaseName(helper.source, "Value"), helper.source, helper.target, "")
+		if err != nil {
+			return "", err
+		}
+		m.body.WriteString(assign)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("\n// %s %s\nfunc (a *%sImpl%s) %s(%s) %s {\n",
+		helper.name, description, g.iface.Name, g.typeParamList(false), helper.name, params, results))
+	sb.WriteString(fmt.Sprintf("\tvar target %s\n\n", targetType))
+	sb.WriteString(m.body.String())
+	sb.WriteString(fmt.Sprintf("\n\t%s\n}\n", finalReturn))
+
+	return sb.String(), nil
+}
+
+// writeNestedFields maps the fields of a nested struct matched by name, with
+// the field tags of both structs and the unmapped field policies of the
+// helper options applied
+func (m *methodWriter) writeNestedFields(helper *nestedHelper) error {
+	_, targetStruct, err := getStruct(helper.target)
+	if err != nil {
+		return err
+	}
+	_, sourceStruct, err := getStruct(helper.source)
+	if err != nil {
+		return err
+	}
+
+	matcher, err := newNameMatcher(helper.options)
+	if err != nil {
+		return err
+	}
+	rules, err := m.g.collectFieldRules(&MethodConfig{}, matcher, targetStruct, sourceStruct)
+	if err != nil {
+		return err
+	}
+
+	method := MethodInfo{Name: helper.name, Position: helper.origin.Position}
+	if err := m.g.checkUnmappedTargets(method, helper.options, m.g.typeString(helper.target), rules, targetStruct); err != nil {
+		return err
+	}
+	if err := m.g.checkUnmappedSources(method, helper.options, m.g.typeString(helper.source), rules, sourceStruct); err != nil {
+		return err
+	}
+
+	return m.writeFieldMappings(rules, targetStruct, sourceStruct)
+}
+
+// isStruct reports whether t is a struct type, named or anonymous
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
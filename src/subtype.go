@@ -0,0 +1,114 @@
+package src
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// subtypeMapping maps one concrete type found behind an interface value
+type subtypeMapping struct {
+	source types.Type // concrete type implementing the source interface
+	target types.Type // type it is mapped to, assignable to the target field
+}
+
+// subtypeMappings resolves the subtype option, a list of Source->Target
+// pairs such as domain.Card->dto.CardDTO, and its fallback
+func (g *generator) subtypeMappings(options Options) ([]subtypeMapping, string, error) {
+	fallback := options["subtypeFallback"]
+	if fallback == "" {
+		fallback = "zero"
+	}
+	if !subtypeFallbacks[fallback] {
+		return nil, "", fmt.Errorf("invalid subtypeFallback %q, expected zero, error or panic", fallback)
+	}
+
+	var mappings []subtypeMapping
+	for _, pair := range options.list("subtype") {
+		sourceExpr, targetExpr, ok := strings.Cut(pair, "->")
+		if !ok {
+			return nil, "", fmt.Errorf("subtype %q must look like Source->Target", pair)
+		}
+		source, err := g.resolveType(strings.TrimSpace(sourceExpr))
+		if err != nil {
+			return nil, "", fmt.Errorf("subtype %q: %v", pair, err)
+		}
+		target, err := g.resolveType(strings.TrimSpace(targetExpr))
+		if err != nil {
+			return nil, "", fmt.Errorf("subtype %q: %v", pair, err)
+		}
+		if types.IsInterface(source) {
+			return nil, "", fmt.Errorf("subtype %q: %s is not a concrete type", pair, source)
+		}
+		mappings = append(mappings, subtypeMapping{source: source, target: target})
+	}
+
+	return mappings, fallback, nil
+}
+
+// assignSubtype renders a type switch over an interface value, mapping each
+// listed concrete type that implements the source interface and whose target
+// fits the target type. ok is false when no subtype applies. A nil value
+// leaves the target alone; any other type is handled by the fallback.
+func (m *methodWriter) assignSubtype(targetExpr, sourceExpr, name string, sourceType, targetType types.Type) (assign string, ok bool, err error) {
+	var cases []subtypeMapping
+	for _, subtype := range m.subtypes {
+		if types.AssignableTo(subtype.source, sourceType) && types.AssignableTo(subtype.target, targetType) {
+			cases = append(cases, subtype)
+		}
+	}
+	if len(cases) == 0 {
+		return "", false, nil
+	}
+
+	concrete := "concrete" + upperFirst(name)
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("\tswitch %s := %s.(type) {\n", concrete, sourceExpr))
+	for _, subtype := range cases {
+		body, err := m.assignConcrete(targetExpr, concrete, name, subtype)
+		if err != nil {
+			return "", true, fmt.Errorf("subtype %s: %v", subtype.source, err)
+		}
+		sb.WriteString(fmt.Sprintf("\tcase %s:\n%s", m.g.typeString(subtype.source), body))
+	}
+
+	// nil is not an unknown type, on update methods with the set strategy it clears the target
+	if m.update && m.nullValue == "set" {
+		sb.WriteString(fmt.Sprintf("\tcase nil:\n\t\t%s = %s\n", targetExpr, zeroValue(targetType, m.g.typeString)))
+	} else if m.subFallback != "zero" {
+		sb.WriteString("\tcase nil:\n")
+	}
+
+	switch m.subFallback {
+	case "error":
+		if m.errReturn == "" {
+			return "", true, fmt.Errorf("subtypeFallback error requires the method to return an error")
+		}
+		fmtName := m.g.addImport("fmt", "fmt")
+		sb.WriteString(fmt.Sprintf("\tdefault:\n\t\terr := %s.Errorf(\"no subtype mapping for %%T in %s\", %s)\n\t\t%s\n",
+			fmtName, name, concrete, m.errReturn))
+	case "panic":
+		fmtName := m.g.addImport("fmt", "fmt")
+		sb.WriteString(fmt.Sprintf("\tdefault:\n\t\tpanic(%s.Sprintf(\"no subtype mapping for %%T in %s\", %s))\n",
+			fmtName, name, concrete))
+	}
+	sb.WriteString("\t}\n")
+
+	return sb.String(), true, nil
+}
+
+// assignConcrete renders one case of a subtype switch. Containers are built
+// in a variable of their own type, as an interface target cannot be indexed.
+func (m *methodWriter) assignConcrete(targetExpr, concrete, name string, subtype subtypeMapping) (string, error) {
+	switch subtype.target.Underlying().(type) {
+	case *types.Slice, *types.Map, *types.Array:
+		mapped := "mapped" + upperFirst(name)
+		assign, err := m.assignValue(mapped, concrete, name, subtype.source, subtype.target, "")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("\t\tvar %s %s\n%s\t\t%s = %s\n", mapped, m.g.typeString(subtype.target), assign, targetExpr, mapped), nil
+	}
+
+	return m.assignValue(targetExpr, concrete, name, subtype.source, subtype.target, "")
+}
@@ -0,0 +1,6 @@
+package other
+
+type User struct {
+	ID   string
+	Name string
+}
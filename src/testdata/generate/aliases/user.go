@@ -0,0 +1,31 @@
+package aliases
+
+import "example.com/aliases/other"
+
+type UserID string
+
+type User struct {
+	ID      UserID
+	Name    string
+	Address struct{ City string }
+}
+
+type UserDTO = other.User
+
+type AddressDTO struct {
+	City string
+}
+
+type Profile struct {
+	Name    string
+	Address AddressDTO
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user User) UserDTO
+	ToProfile(user User) Profile
+	IDString(id UserID) string
+	ParseID(s string) UserID
+	ToDTOs(users []User) []UserDTO
+}
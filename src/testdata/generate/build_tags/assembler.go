@@ -0,0 +1,8 @@
+//go:build !mapmap_alt
+
+package build_tags
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user User) UserDTO
+}
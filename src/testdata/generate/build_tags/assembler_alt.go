@@ -0,0 +1,9 @@
+//go:build mapmap_alt
+
+package build_tags
+
+// mapmap:assembler
+type UserAssembler interface {
+	// mapmap:source:Nickname,target:Name
+	ToDTO(user User) UserDTO
+}
@@ -0,0 +1,7 @@
+package build_tags
+
+// mapmap:assembler
+type UserAssembler interface {
+	// mapmap:source:Nickname,target:Name
+	ToDTO(user User) UserDTO
+}
@@ -0,0 +1,10 @@
+package build_tags
+
+type User struct {
+	Name     string
+	Nickname string
+}
+
+type UserDTO struct {
+	Name string
+}
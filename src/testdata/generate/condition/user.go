@@ -0,0 +1,33 @@
+package condition
+
+type UserDTO struct {
+	Email string
+	Phone string
+	Age   int
+}
+
+// HasEmail reports whether an email was given
+func (d UserDTO) HasEmail() bool {
+	return d.Email != ""
+}
+
+// HasPhone is used for Phone once presenceCheck is set
+func (d UserDTO) HasPhone() bool {
+	return d.Phone != ""
+}
+
+type User struct {
+	Email string
+	Phone string
+	Age   int
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	// mapmap:target:Email,condition:"HasEmail()"
+	// mapmap:target:Age,condition:"src.Age > 0"
+	ToUser(userDTO UserDTO) User
+
+	// mapmap:presenceCheck
+	UpdateUser(userDTO UserDTO, user *User)
+}
@@ -0,0 +1,20 @@
+package asm
+
+import "example.com/config/common"
+
+type Order struct {
+	OrderID int64
+	Amount  int64
+}
+
+type OrderDTO struct {
+	OrderId string
+	Amount  string
+}
+
+// mapmap:assembler config:"common.BaseConfig"
+type OrderAssembler interface {
+	ToDTO(order Order) OrderDTO
+}
+
+var _ common.BaseConfig
@@ -0,0 +1,11 @@
+package common
+
+import "strconv"
+
+// mapmap:config match:"ignoreCase" unmappedTarget:"error"
+type BaseConfig struct{}
+
+// CentsToYuan converts every int64 field mapped to a string
+func (BaseConfig) CentsToYuan(cents int64) string {
+	return strconv.FormatFloat(float64(cents)/100, 'f', 2, 64)
+}
@@ -0,0 +1,27 @@
+package asm
+
+import "example.com/constructor/domain"
+
+type UserDTO struct {
+	Name  string
+	Age   int
+	Email string
+}
+
+type OrderDTO struct {
+	Code  string
+	Total int64
+	Note  string
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	// mapmap:constructor:"NewUser"
+	ToUser(dto UserDTO) (*domain.User, error)
+
+	// NewUser is found without naming it
+	ToDetectedUser(dto UserDTO) (*domain.User, error)
+
+	// mapmap:builder:"NewOrderBuilder"
+	ToOrder(dto OrderDTO) domain.Order
+}
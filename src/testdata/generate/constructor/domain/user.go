@@ -0,0 +1,49 @@
+package domain
+
+import "errors"
+
+type User struct {
+	name  string
+	age   int
+	Email string
+}
+
+// NewUser enforces the invariants of a user
+func NewUser(name string, age int) (*User, error) {
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	return &User{name: name, age: age}, nil
+}
+
+type Order struct {
+	code  string
+	total int64
+	Note  string
+}
+
+type OrderBuilder struct {
+	order Order
+}
+
+// NewOrderBuilder starts building an order
+func NewOrderBuilder() *OrderBuilder {
+	return &OrderBuilder{}
+}
+
+// WithCode sets the order code
+func (b *OrderBuilder) WithCode(code string) *OrderBuilder {
+	b.order.code = code
+	return b
+}
+
+// WithTotal sets the order total
+func (b *OrderBuilder) WithTotal(total int64) *OrderBuilder {
+	b.order.total = total
+	return b
+}
+
+// Build returns the order
+func (b *OrderBuilder) Build() Order {
+	return b.order
+}
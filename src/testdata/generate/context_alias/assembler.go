@@ -0,0 +1,18 @@
+package context_alias
+
+import stdctx "context"
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Name  string
+	Trace string
+}
+
+// mapmap:assembler
+// mapmap:ignoreTarget:"Trace"
+type UserAssembler interface {
+	ToDTO(c stdctx.Context, user User) UserDTO
+}
@@ -0,0 +1,13 @@
+package context_alias
+
+import (
+	gocontext "context"
+	"fmt"
+)
+
+type traceKey struct{}
+
+// mapmap:after
+func (a *UserAssemblerImpl) trace(ctx gocontext.Context, user User, dto *UserDTO) {
+	dto.Trace = fmt.Sprint(ctx.Value(traceKey{}))
+}
@@ -0,0 +1,62 @@
+package context_passthrough
+
+import (
+	"context"
+	"time"
+)
+
+type Order struct {
+	Buyer     User
+	CreatedAt time.Time
+	Items     []Item
+}
+
+type OrderDTO struct {
+	Buyer     UserDTO
+	CreatedAt string
+	Items     []ItemDTO
+}
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Name string
+}
+
+type Item struct {
+	SKU string
+}
+
+type ItemDTO struct {
+	SKU string
+}
+
+// BeforeMap receives the context of the method
+func (o *Order) BeforeMap(ctx context.Context) error {
+	return ctx.Err()
+}
+
+type tenantKey struct{}
+
+// mapmap:config
+type LocaleConfig struct{}
+
+// FormatTime formats times in the zone of the tenant
+func (LocaleConfig) FormatTime(ctx context.Context, t time.Time) string {
+	if zone, ok := ctx.Value(tenantKey{}).(*time.Location); ok {
+		t = t.In(zone)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToUserDTO(ctx context.Context, user User) (UserDTO, error)
+}
+
+// mapmap:assembler uses:"UserAssembler" config:"LocaleConfig"
+type OrderAssembler interface {
+	ToOrderDTO(ctx context.Context, order Order) (OrderDTO, error)
+}
@@ -0,0 +1,29 @@
+package deep_copy
+
+type Limits struct {
+	Max *int
+}
+
+type Config struct {
+	Tags    []string
+	Labels  map[string][]string
+	Limits  *Limits
+	Ports   [2]int
+	Raw     []byte
+	Parents []*Config
+}
+
+type ConfigDTO struct {
+	Tags    []string
+	Labels  map[string][]string
+	Limits  *Limits
+	Ports   [2]int
+	Raw     []byte
+	Parents []*Config
+}
+
+// mapmap:assembler deepCopy:"true"
+type ConfigAssembler interface {
+	// mapmap:target:Raw,deepCopy:false
+	ToDTO(config Config) ConfigDTO
+}
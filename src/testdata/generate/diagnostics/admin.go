@@ -0,0 +1,11 @@
+package diagnostics
+
+type Admin struct {
+	Name string
+}
+
+// mapmap:assembler
+type GuestAssembler interface {
+	// mapmap:source:Name,taget:Title
+	ToDTO(admin Admin) AdminDTO
+}
@@ -0,0 +1,24 @@
+package diagnostics
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Title string `mapmap:"name:Missing"`
+}
+
+type AdminDTO struct {
+	Title string
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user User) UserDTO
+}
+
+// mapmap:assembler
+type AdminAssembler interface {
+	// mapmap:source:Nmae,target:Title
+	ToDTO(user User) AdminDTO
+}
@@ -0,0 +1,21 @@
+package embedded
+
+import "example.com/embedded/order"
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Name string
+}
+
+type UserAssembler interface {
+	ToUserDTO(user User) UserDTO
+}
+
+// mapmap:assembler
+type AppAssembler interface {
+	UserAssembler
+	order.OrderAssembler
+}
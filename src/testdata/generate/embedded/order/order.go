@@ -0,0 +1,17 @@
+package order
+
+type Order struct {
+	ID    string
+	Total int
+}
+
+type OrderDTO struct {
+	ID     string
+	Amount int
+}
+
+// mapmap:assembler
+type OrderAssembler interface {
+	// mapmap:source:Total,target:Amount
+	ToDTO(order Order) OrderDTO
+}
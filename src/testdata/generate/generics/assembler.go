@@ -0,0 +1,17 @@
+package generics
+
+import "example.com/generics/page"
+
+// mapmap:assembler
+type PageAssembler[S, T any] interface {
+	ToPage(p page.Page[S]) page.PageDTO[T]
+	ToEnvelope(e page.Envelope[S]) page.EnvelopeDTO[T]
+
+	// written by hand in item.go, S and T are only known where the assembler is used
+	ToItem(item S) T
+}
+
+// mapmap:assembler
+type UserEnvelopeAssembler interface {
+	ToUserEnvelope(e page.Envelope[page.User]) page.EnvelopeDTO[page.UserDTO]
+}
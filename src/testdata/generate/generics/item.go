@@ -0,0 +1,10 @@
+package generics
+
+// ToItem converts a page item that knows its DTO
+func (a *PageAssemblerImpl[S, T]) ToItem(item S) T {
+	if convertible, ok := any(item).(interface{ ToDTO() T }); ok {
+		return convertible.ToDTO()
+	}
+	var zero T
+	return zero
+}
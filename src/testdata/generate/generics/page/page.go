@@ -0,0 +1,34 @@
+package page
+
+type Page[S any] struct {
+	Items []S
+	Total int
+}
+
+type PageDTO[T any] struct {
+	Items []T
+	Total int
+}
+
+type Envelope[S any] struct {
+	Data S
+	Code int
+}
+
+type EnvelopeDTO[T any] struct {
+	Data T
+	Code int
+}
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Name string
+}
+
+// ToDTO converts a user, ToItem of the assemblers relies on it
+func (u User) ToDTO() UserDTO {
+	return UserDTO{Name: u.Name}
+}
@@ -0,0 +1,14 @@
+package generics_missing
+
+type Page[S any] struct {
+	Items []S
+}
+
+type PageDTO[T any] struct {
+	Items []T
+}
+
+// mapmap:assembler
+type PageAssembler[S, T any] interface {
+	ToPage(p Page[S]) PageDTO[T]
+}
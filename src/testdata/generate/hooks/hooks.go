@@ -0,0 +1,19 @@
+package hooks
+
+import (
+	"errors"
+	"strings"
+)
+
+// mapmap:before
+func (a *UserAssemblerImpl) validate(user User) error {
+	if user.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// mapmap:after
+func (a *UserAssemblerImpl) upper(user User, dto *UserDTO) {
+	dto.Name = strings.ToUpper(dto.Name)
+}
@@ -0,0 +1,30 @@
+package hooks
+
+import "strings"
+
+type User struct {
+	Name string
+}
+
+// BeforeMap runs before the fields of the source are read
+func (u *User) BeforeMap() {
+	u.Name = strings.TrimSpace(u.Name)
+}
+
+type UserDTO struct {
+	Name    string
+	Initial string
+}
+
+// AfterMap runs once the fields of the target are set
+func (d *UserDTO) AfterMap(user User) {
+	if user.Name != "" {
+		d.Initial = user.Name[:1]
+	}
+}
+
+// mapmap:assembler
+// mapmap:ignoreTarget:"Initial"
+type UserAssembler interface {
+	ToDTO(user User) (UserDTO, error)
+}
@@ -0,0 +1,12 @@
+package asm
+
+import (
+	d "example.com/imports/dto"
+	_ "example.com/imports/dtoutil"
+	"example.com/imports/v2model"
+)
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user model.User) d.UserDTO
+}
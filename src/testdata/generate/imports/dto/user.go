@@ -0,0 +1,8 @@
+package dto
+
+import "example.com/imports/legacy/model"
+
+type UserDTO struct {
+	Name    string
+	Profile model.Profile
+}
@@ -0,0 +1,5 @@
+package dtoutil
+
+type UserDTO struct {
+	Wrong bool
+}
@@ -0,0 +1,5 @@
+package model
+
+type Profile struct {
+	Bio string
+}
@@ -0,0 +1,10 @@
+package model
+
+type User struct {
+	Name    string
+	Profile Profile
+}
+
+type Profile struct {
+	Bio string
+}
@@ -0,0 +1,46 @@
+package inherit
+
+type User struct {
+	Name     string
+	Nickname string
+	Age      int
+}
+
+type UserDTO struct {
+	Name     string
+	NickName string
+	Age      int
+}
+
+type UserSummaryDTO struct {
+	Title    string
+	NickName string
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	// mapmap:source:Nickname,target:NickName
+	ToUserDTO(user User) UserDTO
+
+	// mapmap:inherit:"ToUserDTO"
+	// mapmap:source:Name,target:Title
+	ToSummaryDTO(user User) UserSummaryDTO
+}
+
+// mapmap:assembler
+type CyclicAssembler interface {
+	// mapmap:inherit:"ToSummaryDTO"
+	ToUserDTO(user User) UserDTO
+
+	// mapmap:inherit:"ToUserDTO"
+	ToSummaryDTO(user User) UserSummaryDTO
+}
+
+// mapmap:assembler
+type MissingFieldAssembler interface {
+	// mapmap:source:Age,target:Age
+	ToUserDTO(user User) UserDTO
+
+	// mapmap:inherit:"ToUserDTO"
+	ToSummaryDTO(user User) UserSummaryDTO
+}
@@ -0,0 +1,47 @@
+package inverse
+
+import "time"
+
+type User struct {
+	Name      string
+	Nickname  string
+	CreatedAt time.Time
+	Remark    string
+}
+
+type UserAddDTO struct {
+	Name      string
+	NickName  string
+	CreatedAt string
+	Source    string
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	// mapmap:source:Nickname,target:NickName
+	// mapmap:target:CreatedAt,format:"2006-01-02"
+	// mapmap:target:Source,constant:"web"
+	ToAddDTO(user User) (UserAddDTO, error)
+
+	// mapmap:inverse:"ToAddDTO"
+	// mapmap:ignoreSource:"Source"
+	// mapmap:target:Remark,ignore
+	ToAddUser(addDTO UserAddDTO) (User, error)
+}
+
+type Order struct {
+	Channel string
+}
+
+type OrderDTO struct {
+	Channel string
+}
+
+// mapmap:assembler
+type OrderAssembler interface {
+	// mapmap:target:Channel,constant:"web"
+	ToDTO(order Order) OrderDTO
+
+	// mapmap:inverse:"ToDTO"
+	ToOrder(orderDTO OrderDTO) Order
+}
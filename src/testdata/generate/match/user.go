@@ -0,0 +1,27 @@
+package match
+
+type User struct {
+	UserID    int64
+	CreatedAt string
+	Email     string `json:"email_address"`
+	FName     string
+	TitleOld  string
+	Phone     string
+}
+
+type UserDTO struct {
+	UserId       int64
+	Created_At   string
+	EmailAddress string
+	Name         string
+	Title        string
+	PhoneNumber  string `db:"phone"`
+}
+
+// mapmap:assembler match:"ignoreCase,snake,json" stripPrefix:"F" stripSuffix:"Old"
+type UserAssembler interface {
+	ToDTO(user User) UserDTO
+
+	// mapmap:match:"db"
+	ToPhoneDTO(user User) UserDTO
+}
@@ -0,0 +1,15 @@
+package match_ambiguous
+
+type User struct {
+	UserID int64
+	UserId int64
+}
+
+type UserDTO struct {
+	USERID int64
+}
+
+// mapmap:assembler match:"ignoreCase"
+type UserAssembler interface {
+	ToDTO(user User) UserDTO
+}
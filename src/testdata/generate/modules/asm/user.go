@@ -0,0 +1,8 @@
+package asm
+
+import "example.com/lib/model"
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user model.User) model.UserDTO
+}
@@ -0,0 +1,9 @@
+package model
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Name string
+}
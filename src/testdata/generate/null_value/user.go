@@ -0,0 +1,28 @@
+package null_value
+
+type User struct {
+	Name     string
+	Age      int
+	Nickname string
+	Tags     []string
+	Remark   string
+}
+
+type UserPatchDTO struct {
+	Name     *string
+	Age      *int
+	NickName *string
+	Tags     []string
+	Remark   string `mapmap:"default:none"`
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	// mapmap:nullValue:"skip"
+	// mapmap:source:NickName,target:Nickname
+	// mapmap:target:Age,nullValue:"set"
+	ApplyPatch(patch UserPatchDTO, user *User)
+
+	// mapmap:source:NickName,target:Nickname
+	ReplaceUser(patch UserPatchDTO, user *User) error
+}
@@ -0,0 +1,43 @@
+package recursive
+
+type Category struct {
+	Name     string
+	Parent   *Category
+	Children []Category
+	Tree     map[string]*Node
+}
+
+type CategoryDTO struct {
+	Name     string
+	Parent   *CategoryDTO
+	Children []CategoryDTO
+	Tree     map[string]*NodeDTO
+}
+
+type Node struct {
+	Label string
+	Next  *Node
+}
+
+type NodeDTO struct {
+	Label string
+	Next  *NodeDTO
+}
+
+// mapmap:assembler
+type CategoryAssembler interface {
+	ToDTO(category Category) CategoryDTO
+}
+
+type Loop struct {
+	Self []map[string]Loop
+}
+
+type LoopDTO struct {
+	Self []map[string]LoopDTO
+}
+
+// mapmap:assembler
+type LoopAssembler interface {
+	ToDTO(loop Loop) LoopDTO
+}
@@ -0,0 +1,25 @@
+package same_package
+
+import . "example.com/same_package/shared"
+
+type User struct {
+	Name    string
+	secret  string
+	Address Address
+}
+
+type UserDTO struct {
+	Name    string
+	secret  string
+	Address AddressDTO
+}
+
+type AddressDTO struct {
+	City string
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user User) UserDTO
+	ToAddressDTO(address Address) AddressDTO
+}
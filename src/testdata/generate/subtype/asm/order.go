@@ -0,0 +1,13 @@
+package asm
+
+import (
+	"example.com/subtype/domain"
+	"example.com/subtype/dto"
+)
+
+// mapmap:assembler subtype:"domain.Card->dto.CardDTO,*domain.BankTransfer->*dto.BankTransferDTO"
+type OrderAssembler interface {
+	// mapmap:subtypeFallback:"error"
+	ToDTO(order domain.Order) (dto.OrderDTO, error)
+	ToLenientDTO(order domain.Order) dto.OrderDTO
+}
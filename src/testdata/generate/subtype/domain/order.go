@@ -0,0 +1,27 @@
+package domain
+
+type PaymentMethod interface {
+	Pay() string
+}
+
+type Card struct {
+	Number string
+}
+
+func (Card) Pay() string { return "card" }
+
+type BankTransfer struct {
+	IBAN string
+}
+
+func (*BankTransfer) Pay() string { return "transfer" }
+
+type Cash struct{}
+
+func (Cash) Pay() string { return "cash" }
+
+type Order struct {
+	ID       string
+	Payment  PaymentMethod
+	Payments []PaymentMethod
+}
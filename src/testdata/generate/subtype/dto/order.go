@@ -0,0 +1,17 @@
+package dto
+
+type PaymentDTO interface{}
+
+type CardDTO struct {
+	Number string
+}
+
+type BankTransferDTO struct {
+	IBAN string
+}
+
+type OrderDTO struct {
+	ID       string
+	Payment  PaymentDTO
+	Payments []PaymentDTO
+}
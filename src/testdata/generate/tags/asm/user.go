@@ -0,0 +1,13 @@
+package asm
+
+import (
+	"example.com/tags/domain"
+	"example.com/tags/dto"
+)
+
+// mapmap:assembler
+type UserAssembler interface {
+	// method comments win over the mapsource tag of Title
+	// mapmap:source:Name,target:Title
+	ToDTO(user domain.User) dto.UserDTO
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+type User struct {
+	Name      string
+	Nickname  string
+	CreatedAt time.Time
+	Remark    string
+	Password  string `mapmap:"-"`
+	Age       int    `mapmap:"name:Years"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+type UserDTO struct {
+	NickName  string `mapsource:"Nickname"`
+	FullName  string `mapmap:"name:Name"`
+	CreatedAt string `mapmap:"format:2006-01-02"`
+	Remark    string `mapmap:"default:none"`
+	Password  string `mapmap:"-"`
+	Years     int
+	Title     string `mapsource:"Nickname"`
+}
@@ -0,0 +1,28 @@
+package unmapped_source
+
+type UserDTO struct {
+	Name     string
+	Password string
+	Salt     string
+	Remark   string
+	Tags     []string
+}
+
+type User struct {
+	Name     string
+	TagCount int
+}
+
+// mapmap:assembler unmappedSource:"error"
+type UserAssembler interface {
+	// mapmap:ignoreSource:"Password,Salt"
+	// mapmap:target:TagCount,expression:"len(src.Tags)"
+	ToUser(userDTO UserDTO) User
+}
+
+// mapmap:assembler unmappedSource:"error"
+type CheckedAssembler interface {
+	// mapmap:ignoreSource:"Password,Salt,Remark"
+	// mapmap:target:TagCount,expression:"len(src.Tags)"
+	ToCheckedUser(userDTO UserDTO) User
+}
@@ -0,0 +1,22 @@
+package unmapped_target
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Name  string
+	Email string
+	Phone string
+}
+
+// mapmap:assembler unmappedTarget:"error"
+type UserAssembler interface {
+	ToDTO(user User) UserDTO
+}
+
+// mapmap:assembler unmappedTarget:"error"
+type LooseAssembler interface {
+	// mapmap:unmappedTarget:"warn"
+	ToLooseDTO(user User) UserDTO
+}
@@ -0,0 +1,14 @@
+package geo
+
+type Address struct {
+	City string
+}
+
+type AddressDTO struct {
+	City string
+}
+
+// mapmap:assembler
+type AddressAssembler interface {
+	ToAddressDTO(address Address) AddressDTO
+}
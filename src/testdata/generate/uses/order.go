@@ -0,0 +1,35 @@
+package uses
+
+import "example.com/uses/geo"
+
+type User struct {
+	Name string
+}
+
+type UserDTO struct {
+	Name string
+}
+
+type Order struct {
+	Buyer    User
+	Watchers []User
+	Owner    *User
+	Address  geo.Address
+}
+
+type OrderDTO struct {
+	Buyer    UserDTO
+	Watchers []UserDTO
+	Owner    *UserDTO
+	Address  geo.AddressDTO
+}
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToUserDTO(user User) UserDTO
+}
+
+// mapmap:assembler uses:"UserAssembler,geo.AddressAssembler" unmappedTarget:"error"
+type OrderAssembler interface {
+	ToOrderDTO(order Order) OrderDTO
+}
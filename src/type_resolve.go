@@ -0,0 +1,356 @@
+package src
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// loadTypeParams creates the type parameters of a generic assembler interface
+// so method types such as Page[S] can be instantiated with them
+func (g *generator) loadTypeParams() error {
+	g.typeParams = make(map[string]*types.TypeParam)
+	for _, param := range g.iface.TypeParams {
+		typeName := types.NewTypeName(token.NoPos, nil, param.Name, nil)
+		g.typeParams[param.Name] = types.NewTypeParam(typeName, nil)
+	}
+
+	// constraints may refer to other type parameters, so they are set once all exist
+	for _, param := range g.iface.TypeParams {
+		constraint, err := g.resolveConstraint(param.Type)
+		if err != nil {
+			return fmt.Errorf("type parameter %s: %v", param.Name, err)
+		}
+		g.typeParams[param.Name].SetConstraint(constraint)
+	}
+
+	return nil
+}
+
+// typeParamList renders the type parameters of the implementation, as
+// declared ("[S any, T any]") or as used ("[S, T]"), or nothing for a
+// non-generic interface
+func (g *generator) typeParamList(withConstraints bool) string {
+	if len(g.iface.TypeParams) == 0 {
+		return ""
+	}
+
+	params := make([]string, 0, len(g.iface.TypeParams))
+	for _, param := range g.iface.TypeParams {
+		if withConstraints {
+			params = append(params, param.Name+" "+param.Type)
+		} else {
+			params = append(params, param.Name)
+		}
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// resolveType evaluates a type written in the interface file, instantiating
+// generic types with their type arguments
+func (g *generator) resolveType(expr string) (types.Type, error) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %s: %v", expr, err)
+	}
+
+	return g.resolveTypeExpr(node)
+}
+
+// resolveTypeExpr evaluates a parsed type expression
+func (g *generator) resolveTypeExpr(expr ast.Expr) (types.Type, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		// embedded interfaces are not generic, their types never name a type parameter
+		if param, ok := g.typeParams[e.Name]; ok && g.scope == nil {
+			return param, nil
+		}
+		return g.lookupUnqualified(e.Name)
+
+	case *ast.SelectorExpr:
+		qualifier, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("invalid type %s", types.ExprString(e))
+		}
+		pkg, err := g.importedPackage(qualifier.Name)
+		if err != nil {
+			return nil, err
+		}
+		typeName, ok := pkg.Scope().Lookup(e.Sel.Name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in package %s", e.Sel.Name, pkg.Path())
+		}
+		return typeName.Type(), nil
+
+	case *ast.StarExpr:
+		elem, err := g.resolveTypeExpr(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewPointer(elem), nil
+
+	case *ast.ArrayType:
+		elem, err := g.resolveTypeExpr(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+		if e.Len == nil {
+			return types.NewSlice(elem), nil
+		}
+		length, ok := e.Len.(*ast.BasicLit)
+		if !ok || length.Kind != token.INT {
+			return nil, fmt.Errorf("array length %s must be an integer literal", types.ExprString(e.Len))
+		}
+		n, err := strconv.ParseInt(length.Value, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewArray(elem, n), nil
+
+	case *ast.MapType:
+		key, err := g.resolveTypeExpr(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := g.resolveTypeExpr(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewMap(key, value), nil
+
+	case *ast.IndexExpr:
+		return g.instantiate(e.X, []ast.Expr{e.Index})
+
+	case *ast.IndexListExpr:
+		return g.instantiate(e.X, e.Indices)
+
+	case *ast.ParenExpr:
+		return g.resolveTypeExpr(e.X)
+
+	case *ast.StructType:
+		return g.resolveStruct(e)
+
+	case *ast.InterfaceType:
+		if e.Methods == nil || len(e.Methods.List) == 0 {
+			return types.NewInterfaceType(nil, nil).Complete(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", types.ExprString(expr))
+}
+
+// resolveStruct evaluates an anonymous struct type, keeping field names,
+// embedded fields and tags
+func (g *generator) resolveStruct(expr *ast.StructType) (types.Type, error) {
+	var fields []*types.Var
+	var tags []string
+	for _, field := range expr.Fields.List {
+		fieldType, err := g.resolveTypeExpr(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		tag := ""
+		if field.Tag != nil {
+			tag, err = strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(field.Names) == 0 {
+			// an embedded field is named after its type
+			name := types.ExprString(field.Type)
+			name = name[strings.LastIndex(name, ".")+1:]
+			name = strings.TrimPrefix(name, "*")
+			fields = append(fields, types.NewField(token.NoPos, nil, name, fieldType, true))
+			tags = append(tags, tag)
+			continue
+		}
+		for _, name := range field.Names {
+			fields = append(fields, types.NewField(token.NoPos, nil, name.Name, fieldType, false))
+			tags = append(tags, tag)
+		}
+	}
+
+	return types.NewStruct(fields, tags), nil
+}
+
+// instantiate resolves a generic type with its type arguments, like Envelope[domain.User]
+func (g *generator) instantiate(genericExpr ast.Expr, argExprs []ast.Expr) (types.Type, error) {
+	generic, err := g.resolveTypeExpr(genericExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]types.Type, 0, len(argExprs))
+	for _, argExpr := range argExprs {
+		arg, err := g.resolveTypeExpr(argExpr)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+
+	instance, err := types.Instantiate(g.typeContext, generic, args, true)
+	if err != nil {
+		return nil, fmt.Errorf("cannot instantiate %s: %v", types.ExprString(genericExpr), err)
+	}
+
+	return instance, nil
+}
+
+// resolveConstraint evaluates a type parameter constraint: an interface such
+// as any or fmt.Stringer, or a union of terms such as ~int | ~string
+func (g *generator) resolveConstraint(expr string) (types.Type, error) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint %s: %v", expr, err)
+	}
+
+	var terms []*types.Term
+	var collect func(ast.Expr) error
+	collect = func(e ast.Expr) error {
+		switch term := e.(type) {
+		case *ast.BinaryExpr:
+			if term.Op != token.OR {
+				return fmt.Errorf("invalid constraint %s", expr)
+			}
+			if err := collect(term.X); err != nil {
+				return err
+			}
+			return collect(term.Y)
+		case *ast.UnaryExpr:
+			if term.Op != token.TILDE {
+				return fmt.Errorf("invalid constraint %s", expr)
+			}
+			t, err := g.resolveTypeExpr(term.X)
+			if err != nil {
+				return err
+			}
+			terms = append(terms, types.NewTerm(true, t))
+			return nil
+		}
+
+		t, err := g.resolveTypeExpr(e)
+		if err != nil {
+			return err
+		}
+		terms = append(terms, types.NewTerm(false, t))
+		return nil
+	}
+	if err := collect(node); err != nil {
+		return nil, err
+	}
+
+	// a single interface is the constraint itself, anything else is wrapped in an implicit interface
+	if len(terms) == 1 && !terms[0].Tilde() && types.IsInterface(terms[0].Type()) {
+		return terms[0].Type(), nil
+	}
+	constraint := types.NewInterfaceType(nil, []types.Type{types.NewUnion(terms)})
+	constraint.MarkImplicit()
+	return constraint.Complete(), nil
+}
+
+// lookupUnqualified resolves a type written without a package qualifier the
+// way the compiler would: declared in the package of the file it is written
+// in, usually the assembler's own, then brought in by a dot-import, then
+// predeclared
+func (g *generator) lookupUnqualified(name string) (types.Type, error) {
+	local, localErr := g.importer.localPackage()
+	if g.scope != nil {
+		local, localErr = getLocalPackageInfo(g.importer, g.scope.PackagePath)
+	}
+	if localErr == nil {
+		if typeName, ok := local.Scope().Lookup(name).(*types.TypeName); ok {
+			return typeName.Type(), nil
+		}
+	}
+
+	for _, imported := range g.scopeImports() {
+		if imported.Name != "." {
+			continue
+		}
+		pkg, err := getLocalPackageInfo(g.importer, imported.Path)
+		if err != nil {
+			return nil, err
+		}
+		if typeName, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok && typeName.Exported() {
+			return typeName.Type(), nil
+		}
+	}
+
+	if typeName, ok := types.Universe.Lookup(name).(*types.TypeName); ok {
+		return typeName.Type(), nil
+	}
+	if localErr != nil {
+		return nil, fmt.Errorf("unknown type %s: %v", name, localErr)
+	}
+	return nil, fmt.Errorf("unknown type %s", name)
+}
+
+// importedPackage loads the package the interface file imports under a
+// name: the alias of the import, or else the name the package declares,
+// which need not match the last element of its path. Only the package
+// clauses of unaliased imports are read, the matching package alone is loaded.
+func (g *generator) importedPackage(name string) (*types.Package, error) {
+	var nameErr error
+	for _, imported := range g.scopeImports() {
+		if imported.Name == "_" || imported.Name == "." {
+			continue
+		}
+		if imported.Name != "" {
+			if imported.Name == name {
+				return getLocalPackageInfo(g.importer, imported.Path)
+			}
+			continue
+		}
+
+		pkgName, err := g.importer.packageName(imported.Path)
+		if err != nil {
+			// an unrelated import that cannot be found only matters if nothing matches
+			if nameErr == nil {
+				nameErr = err
+			}
+			continue
+		}
+		if pkgName == name {
+			return getLocalPackageInfo(g.importer, imported.Path)
+		}
+	}
+
+	if nameErr != nil {
+		return nil, fmt.Errorf("package %s is not imported: %v", name, nameErr)
+	}
+	return nil, fmt.Errorf("package %s is not imported", name)
+}
+
+// scopeImports returns the imports of the file the types being resolved are
+// written in: the interface file, or the file declaring an embedded interface
+func (g *generator) scopeImports() []ImportInfo {
+	if g.scope != nil {
+		return g.scope.Imports
+	}
+	return g.iface.Imports
+}
+
+// getStruct returns the struct type behind the source or target of a method,
+// dereferencing a pointer and resolving aliases. The named type is nil for an
+// anonymous struct.
+func getStruct(t types.Type) (*types.Named, *types.Struct, error) {
+	t = types.Unalias(t)
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = types.Unalias(pointer.Elem())
+	}
+
+	structType, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a struct", t)
+	}
+
+	named, _ := t.(*types.Named)
+	return named, structType, nil
+}
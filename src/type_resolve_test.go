@@ -0,0 +1,54 @@
+package src
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestImportedPackage(t *testing.T) {
+	isolateGoEnv(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":           "module example.com/m\n\ngo 1.22\n",
+		"v2model/user.go":  "package model\n\ntype User struct {\n\tName string\n}\n",
+		"dto/user.go":      "package dto\n\ntype UserDTO struct {\n\tName string\n}\n",
+		"unused/unused.go": "package unused\n\nvar Unused int\n",
+		"asm/assembler.go": `package asm
+
+import (
+	"example.com/m/unused"
+	"example.com/m/dto"
+	"example.com/m/v2model"
+)
+
+var _ = unused.Unused
+
+// mapmap:assembler
+type UserAssembler interface {
+	ToDTO(user model.User) dto.UserDTO
+}
+`,
+	})
+
+	loader := NewLoader(BuildOptions{})
+	interfaces, err := ParseFile(loader, filepath.Join(dir, "asm", "assembler.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, iface := range interfaces {
+		if err := ProcessInterface(loader, iface, filepath.Join(dir, "asm")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// model is found by the name its package declares, the imports that are
+	// not referenced are never type-checked
+	for _, loaded := range []string{"v2model", "dto"} {
+		if _, ok := loader.packages[filepath.Join(dir, loaded)]; !ok {
+			t.Errorf("package %s was not loaded", loaded)
+		}
+	}
+	if _, ok := loader.packages[filepath.Join(dir, "unused")]; ok {
+		t.Error("package unused was type-checked although no type refers to it")
+	}
+}
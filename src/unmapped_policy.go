@@ -0,0 +1,146 @@
+package src
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+)
+
+// checkUnmappedTargets applies the unmappedTarget policy: every accessible
+// target field must be populated by a rule or explicitly ignored
+func (g *generator) checkUnmappedTargets(method MethodInfo, options Options, targetType string, rules map[string]FieldRule, targetStruct *types.Struct) error {
+	policy, err := unmappedPolicy(options, "unmappedTarget")
+	if err != nil || policy == "ignore" {
+		return err
+	}
+
+	var unmapped []error
+	for i := range targetStruct.NumFields() {
+		field := targetStruct.Field(i)
+		if !g.accessible(field) {
+			continue
+		}
+		if rule, ok := rules[field.Name()]; ok && rule.mapped() {
+			continue
+		}
+
+		unmapped = append(unmapped, errorAt(method.Position, "method %s: target field %s.%s is not mapped",
+			method.Name, targetType, field.Name()))
+	}
+
+	return g.reportUnmapped(policy, unmapped)
+}
+
+// checkUnmappedSources applies the unmappedSource policy: every accessible
+// source field must be read by a rule, listed in ignoreSource or tagged mapmap:"-".
+// Fields an expression or condition reads as src.<Field> count as read.
+func (g *generator) checkUnmappedSources(method MethodInfo, options Options, sourceType string, rules map[string]FieldRule, sourceStruct *types.Struct) error {
+	policy, err := unmappedPolicy(options, "unmappedSource")
+	if err != nil {
+		return err
+	}
+
+	// ignoreSource must name real fields even when the policy is ignore
+	exempt := make(map[string]bool)
+	for _, name := range options.list("ignoreSource") {
+		if findField(sourceStruct, name) == nil {
+			return errorAt(method.Position, "method %s: ignoreSource field %s does not exist in %s",
+				method.Name, name, sourceType)
+		}
+		exempt[name] = true
+	}
+	if policy == "ignore" {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Ignore {
+			continue
+		}
+		if rule.Source != "" {
+			used[rule.Source] = true
+		}
+		for _, expr := range []string{rule.Expression, rule.Condition} {
+			for _, name := range sourceSelectors(expr) {
+				used[name] = true
+			}
+		}
+	}
+
+	var unmapped []error
+	for i := range sourceStruct.NumFields() {
+		field := sourceStruct.Field(i)
+		if !g.accessible(field) || used[field.Name()] || exempt[field.Name()] {
+			continue
+		}
+		if tagRule, ok, _ := parseFieldTag(field.Name(), sourceStruct.Tag(i), true); ok && tagRule.Ignore {
+			continue
+		}
+
+		unmapped = append(unmapped, errorAt(method.Position, "method %s: source field %s.%s is not mapped",
+			method.Name, sourceType, field.Name()))
+	}
+
+	return g.reportUnmapped(policy, unmapped)
+}
+
+// sourceSelectors returns the names an expression selects from src, the
+// source value. An expression that does not parse selects nothing; it is
+// reported when the assignment is generated.
+func sourceSelectors(expr string) []string {
+	if expr == "" {
+		return nil
+	}
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if selector, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok && ident.Name == "src" {
+				names = append(names, selector.Sel.Name)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// unmappedPolicy reads and validates an unmapped field policy, defaulting to ignore
+func unmappedPolicy(options Options, key string) (string, error) {
+	policy := options[key]
+	if policy == "" {
+		return "ignore", nil
+	}
+	if !unmappedPolicies[policy] {
+		return "", fmt.Errorf("invalid %s policy %q, expected ignore, warn or error", key, policy)
+	}
+
+	return policy, nil
+}
+
+// reportUnmapped prints the findings as warnings or joins them into one error
+func (g *generator) reportUnmapped(policy string, unmapped []error) error {
+	if len(unmapped) == 0 {
+		return nil
+	}
+
+	if policy == "warn" {
+		for _, err := range unmapped {
+			g.warn(err)
+		}
+		return nil
+	}
+
+	return errors.Join(unmapped...)
+}
+
+// mapped reports whether a rule populates its target or deliberately leaves it alone
+func (r FieldRule) mapped() bool {
+	return r.Ignore || r.valueSources() > 0 || r.Default != ""
+}
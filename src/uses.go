@@ -0,0 +1,176 @@
+package src
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// usedAssembler is an assembler the implementation delegates to
+type usedAssembler struct {
+	field    string          // field of the implementation holding it
+	typeName *types.TypeName // the assembler interface
+}
+
+// loadUses resolves the assemblers listed in uses and registers their
+// methods as converters for the type pairs they map. Converters of an
+// embedded assembler are only offered to the methods it contributes.
+func (g *generator) loadUses(refs []TypeRef, owner *EmbeddedAssembler) error {
+	fields := make(map[string]bool)
+	for _, ref := range refs {
+		typeName, err := lookupTypeRef(g.importer, ref)
+		if err != nil {
+			return fmt.Errorf("failed to load used assembler %s: %v", ref.Name, err)
+		}
+		if !types.IsInterface(typeName.Type()) {
+			return fmt.Errorf("used assembler %s is not an interface", ref.Name)
+		}
+
+		field := lowerFirst(ref.Name)
+		if fields[field] {
+			return fmt.Errorf("used assemblers %s clash on field name %s", ref.Name, field)
+		}
+		fields[field] = true
+
+		if err := g.addUsed(field, typeName); err != nil {
+			return err
+		}
+		g.converters = append(g.converters, ownedConverters(methodConverters(typeName.Type(), "a."+field), owner)...)
+	}
+
+	return nil
+}
+
+// addUsed adds a field holding a used assembler. Assemblers used by more than
+// one embedded assembler share the field.
+func (g *generator) addUsed(field string, typeName *types.TypeName) error {
+	for _, used := range g.uses {
+		if used.field != field {
+			continue
+		}
+		if types.Identical(used.typeName.Type(), typeName.Type()) {
+			return nil
+		}
+		return fmt.Errorf("used assemblers %s and %s clash on field name %s",
+			used.typeName.Type(), typeName.Type(), field)
+	}
+
+	g.uses = append(g.uses, usedAssembler{field: field, typeName: typeName})
+	return nil
+}
+
+// loadConfig resolves a shared config, embeds it in the implementation and
+// registers its methods as converters. A config shared by several embedded
+// assemblers is embedded once.
+func (g *generator) loadConfig(ref *ConfigInfo, owner *EmbeddedAssembler) error {
+	config, err := lookupTypeRef(g.importer, ref.TypeRef)
+	if err != nil {
+		return fmt.Errorf("failed to load config %s: %v", ref.Name, err)
+	}
+
+	embedded := false
+	for _, other := range g.configs {
+		if types.Identical(other.Type(), config.Type()) {
+			embedded = true
+			break
+		}
+		if other.Name() == config.Name() {
+			return fmt.Errorf("configs %s and %s clash on field name %s", other.Type(), config.Type(), config.Name())
+		}
+	}
+	if !embedded {
+		g.configs = append(g.configs, config)
+	}
+
+	g.converters = append(g.converters, ownedConverters(methodConverters(config.Type(), "a."+config.Name()), owner)...)
+	return nil
+}
+
+// ownedConverters marks converters as belonging to an embedded assembler
+func ownedConverters(converters []converter, owner *EmbeddedAssembler) []converter {
+	for i := range converters {
+		converters[i].owner = owner
+	}
+	return converters
+}
+
+// loadEmbeddedAssemblers loads the config and used assemblers of every
+// embedded assembler a method comes from, including the ones embedding it
+func (g *generator) loadEmbeddedAssemblers() error {
+	loaded := make(map[*EmbeddedAssembler]bool)
+	for _, method := range g.iface.Methods {
+		for assembler := method.Assembler; assembler != nil && !loaded[assembler]; assembler = assembler.Outer {
+			loaded[assembler] = true
+
+			if err := g.loadUses(assembler.Uses, assembler); err != nil {
+				return atPosition(assembler.Position, withContext(err, "embedded assembler %s", assembler.Name))
+			}
+			if assembler.Config != nil {
+				if err := g.loadConfig(assembler.Config, assembler); err != nil {
+					return atPosition(assembler.Position, withContext(err, "embedded assembler %s", assembler.Name))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// assemblerOptions returns the options in effect for the methods of an
+// embedded assembler: its own options over its config options, over the
+// options of the assembler embedding it, down to the interface options
+func (g *generator) assemblerOptions(assembler *EmbeddedAssembler) Options {
+	if assembler == nil {
+		return g.options
+	}
+
+	options := g.assemblerOptions(assembler.Outer)
+	if assembler.Config != nil {
+		options = options.merge(assembler.Config.Options)
+	}
+	return options.merge(assembler.Options)
+}
+
+// subtypeScope returns where the subtype option in effect for a method is
+// written: its comments, an embedded assembler or the interface
+func subtypeScope(method MethodInfo, config *MethodConfig) *TypeScope {
+	if _, ok := config.Options["subtype"]; ok {
+		return method.Scope
+	}
+	for assembler := method.Assembler; assembler != nil; assembler = assembler.Outer {
+		if _, ok := assembler.Options["subtype"]; ok {
+			return assembler.Scope
+		}
+	}
+	return nil
+}
+
+// generateConstructor renders New<Impl> taking every dependency that has no
+// usable zero value: used assemblers and interface configs
+func (g *generator) generateConstructor(implName string) string {
+	type dependency struct{ param, field, typ string }
+
+	var dependencies []dependency
+	for _, config := range g.configs {
+		if types.IsInterface(config.Type()) {
+			dependencies = append(dependencies, dependency{lowerFirst(config.Name()), config.Name(), g.typeString(config.Type())})
+		}
+	}
+	for _, used := range g.uses {
+		dependencies = append(dependencies, dependency{used.field, used.field, g.typeString(used.typeName.Type())})
+	}
+	if len(dependencies) == 0 {
+		return ""
+	}
+
+	params := make([]string, 0, len(dependencies))
+	fields := make([]string, 0, len(dependencies))
+	for _, dep := range dependencies {
+		params = append(params, dep.param+" "+dep.typ)
+		fields = append(fields, "\t\t"+dep.field+": "+dep.param+",\n")
+	}
+
+	instance := implName + g.typeParamList(false)
+	return fmt.Sprintf("// New%s creates %s with the dependencies it delegates to\nfunc New%s%s(%s) *%s {\n\treturn &%s{\n%s\t}\n}\n\n",
+		implName, implName, implName, g.typeParamList(true), strings.Join(params, ", "), instance, instance, strings.Join(fields, ""))
+}